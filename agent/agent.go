@@ -0,0 +1,162 @@
+// Package agent implementa un loop tool-calling che tratta il recupero
+// documentale come una funzione invocabile dal modello, così da poter
+// rispondere a domande multi-hop ("confronta cosa dice pagina 3 su X con
+// pagina 10") che la pipeline one-shot a top-K fisso non può gestire.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Document è la rappresentazione minima di un chunk recuperato di cui
+// l'agente ha bisogno per costruire il contesto e citare le fonti.
+type Document struct {
+	ID      string
+	Content string
+	Page    int
+}
+
+// Retriever astrae l'accesso al vector store su cui girano le funzioni
+// invocabili dal modello (ricerca per similarità, lettura di una pagina,
+// elenco delle pagine disponibili).
+type Retriever interface {
+	SearchSimilar(query string, topK int) ([]Document, error)
+	ListPages() []int
+	FetchPage(pageNumber int) (string, error)
+}
+
+// Generator astrae la chiamata al modello di chat: l'agente lo invoca a
+// ogni turno passandogli il transcript accumulato finora.
+type Generator interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// ToolCall è l'invocazione di funzione estratta dall'output del modello.
+type ToolCall struct {
+	Name string                 `json:"tool"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// Step è un turno del loop: il testo emesso dal modello, l'eventuale
+// tool call individuata al suo interno e il risultato della sua esecuzione.
+type Step struct {
+	ModelOutput string
+	Tool        *ToolCall
+	ToolResult  string
+}
+
+// Agent esegue il loop "genera, eventualmente chiama uno strumento,
+// osserva il risultato" finché il modello non produce una risposta
+// finale o si raggiunge MaxSteps.
+type Agent struct {
+	Retriever Retriever
+	Generator Generator
+	MaxSteps  int
+}
+
+// New crea un Agent pronto all'uso. maxSteps <= 0 usa un default di 5.
+func New(retriever Retriever, generator Generator, maxSteps int) *Agent {
+	if maxSteps <= 0 {
+		maxSteps = 5
+	}
+	return &Agent{Retriever: retriever, Generator: generator, MaxSteps: maxSteps}
+}
+
+const systemPrompt = `Sei un assistente che risponde a domande su un documento, usando gli strumenti disponibili quando servono più fonti per rispondere.
+
+Strumenti disponibili (invocali scrivendo UN SOLO blocco di codice JSON in questo formato, nient'altro):
+` + "```json\n{\"tool\": \"search_documents\", \"args\": {\"query\": \"...\", \"top_k\": 4}}\n```" + `
+` + "```json\n{\"tool\": \"fetch_page\", \"args\": {\"page_number\": 3}}\n```" + `
+` + "```json\n{\"tool\": \"list_pages\", \"args\": {}}\n```" + `
+
+Quando hai raccolto abbastanza informazioni, rispondi in italiano con il testo finale, senza alcun blocco JSON.`
+
+var toolCallPattern = regexp.MustCompile("(?s)```(?:json)?\\s*(\\{.*?\\})\\s*```")
+
+// Run esegue il loop fino a una risposta finale o al limite di passi,
+// restituendo la risposta e la traccia completa di ragionamento/strumenti.
+func (a *Agent) Run(ctx context.Context, question string) (string, []Step, error) {
+	transcript := systemPrompt + "\n\nDomanda: " + question + "\n"
+	var trace []Step
+
+	for i := 0; i < a.MaxSteps; i++ {
+		output, err := a.Generator.Generate(ctx, transcript)
+		if err != nil {
+			return "", trace, fmt.Errorf("errore generazione al passo %d: %v", i+1, err)
+		}
+
+		call, ok := parseToolCall(output)
+		if !ok {
+			trace = append(trace, Step{ModelOutput: output})
+			return strings.TrimSpace(output), trace, nil
+		}
+
+		result, err := a.execute(call)
+		if err != nil {
+			result = fmt.Sprintf("errore strumento %s: %v", call.Name, err)
+		}
+
+		trace = append(trace, Step{ModelOutput: output, Tool: &call, ToolResult: result})
+		transcript += fmt.Sprintf("\nRisultato di %s:\n%s\n", call.Name, result)
+	}
+
+	return "", trace, fmt.Errorf("raggiunto il limite di %d passi senza una risposta finale", a.MaxSteps)
+}
+
+func parseToolCall(output string) (ToolCall, bool) {
+	match := toolCallPattern.FindStringSubmatch(output)
+	if match == nil {
+		return ToolCall{}, false
+	}
+
+	var call ToolCall
+	if err := json.Unmarshal([]byte(match[1]), &call); err != nil || call.Name == "" {
+		return ToolCall{}, false
+	}
+
+	return call, true
+}
+
+func (a *Agent) execute(call ToolCall) (string, error) {
+	switch call.Name {
+	case "search_documents":
+		query, _ := call.Args["query"].(string)
+		topK := 4
+		if v, ok := call.Args["top_k"].(float64); ok {
+			topK = int(v)
+		}
+
+		docs, err := a.Retriever.SearchSimilar(query, topK)
+		if err != nil {
+			return "", err
+		}
+
+		var b strings.Builder
+		for i, doc := range docs {
+			fmt.Fprintf(&b, "Sezione %d (Pagina %d, ID %s):\n%s\n\n", i+1, doc.Page, doc.ID, doc.Content)
+		}
+		return b.String(), nil
+
+	case "fetch_page":
+		pageNumber := 0
+		if v, ok := call.Args["page_number"].(float64); ok {
+			pageNumber = int(v)
+		}
+		return a.Retriever.FetchPage(pageNumber)
+
+	case "list_pages":
+		pages := a.Retriever.ListPages()
+		parts := make([]string, len(pages))
+		for i, p := range pages {
+			parts[i] = fmt.Sprintf("%d", p)
+		}
+		return strings.Join(parts, ", "), nil
+
+	default:
+		return "", fmt.Errorf("strumento sconosciuto: %s", call.Name)
+	}
+}
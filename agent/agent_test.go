@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeRetriever è un Retriever in memoria minimo, sufficiente a esercitare
+// le tre funzioni invocabili dall'agente senza un vero RAGChatbot.
+type fakeRetriever struct{}
+
+func (fakeRetriever) SearchSimilar(query string, topK int) ([]Document, error) {
+	return []Document{{ID: "doc1", Content: "contenuto di " + query, Page: 1}}, nil
+}
+
+func (fakeRetriever) ListPages() []int {
+	return []int{1, 2}
+}
+
+func (fakeRetriever) FetchPage(pageNumber int) (string, error) {
+	if pageNumber != 1 {
+		return "", fmt.Errorf("pagina %d non trovata", pageNumber)
+	}
+	return "contenuto pagina 1", nil
+}
+
+// scriptedGenerator restituisce in ordine gli output preparati dal test,
+// uno per ogni chiamata a Generate, per simulare un modello che prima
+// invoca uno strumento e poi risponde.
+type scriptedGenerator struct {
+	outputs []string
+	calls   int
+}
+
+func (g *scriptedGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	if g.calls >= len(g.outputs) {
+		return "", fmt.Errorf("nessun altro output programmato (chiamata %d)", g.calls+1)
+	}
+	out := g.outputs[g.calls]
+	g.calls++
+	return out, nil
+}
+
+func TestRunMultiStepToolCallThenFinalAnswer(t *testing.T) {
+	gen := &scriptedGenerator{outputs: []string{
+		"```json\n{\"tool\": \"search_documents\", \"args\": {\"query\": \"test\", \"top_k\": 4}}\n```",
+		"Risposta finale basata sui documenti recuperati.",
+	}}
+
+	a := New(fakeRetriever{}, gen, 5)
+	answer, trace, err := a.Run(context.Background(), "domanda di prova")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if answer != "Risposta finale basata sui documenti recuperati." {
+		t.Fatalf("answer = %q", answer)
+	}
+	if len(trace) != 2 {
+		t.Fatalf("trace = %d passi, attesi 2", len(trace))
+	}
+	if trace[0].Tool == nil || trace[0].Tool.Name != "search_documents" {
+		t.Fatalf("trace[0].Tool = %+v, atteso search_documents", trace[0].Tool)
+	}
+	if !strings.Contains(trace[0].ToolResult, "doc1") {
+		t.Fatalf("trace[0].ToolResult = %q, attesa menzione di doc1", trace[0].ToolResult)
+	}
+	if trace[1].Tool != nil {
+		t.Fatalf("trace[1].Tool = %+v, atteso nil per il passo finale", trace[1].Tool)
+	}
+}
+
+func TestRunUnparseableOutputIsTreatedAsFinalAnswer(t *testing.T) {
+	gen := &scriptedGenerator{outputs: []string{
+		"```json\n{questo non e JSON valido\n```",
+	}}
+
+	a := New(fakeRetriever{}, gen, 5)
+	answer, trace, err := a.Run(context.Background(), "domanda di prova")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if answer != "```json\n{questo non e JSON valido\n```" {
+		t.Fatalf("answer = %q, atteso l'output grezzo invariato", answer)
+	}
+	if len(trace) != 1 || trace[0].Tool != nil {
+		t.Fatalf("trace = %+v, atteso un solo passo senza tool call", trace)
+	}
+}
+
+func TestRunMaxStepsExceededReturnsError(t *testing.T) {
+	toolCall := "```json\n{\"tool\": \"list_pages\", \"args\": {}}\n```"
+	gen := &scriptedGenerator{outputs: []string{toolCall, toolCall, toolCall}}
+
+	a := New(fakeRetriever{}, gen, 3)
+	_, trace, err := a.Run(context.Background(), "domanda di prova")
+	if err == nil {
+		t.Fatalf("Run doveva fallire per limite di passi raggiunto")
+	}
+	if len(trace) != 3 {
+		t.Fatalf("trace = %d passi, attesi 3 (MaxSteps)", len(trace))
+	}
+}
+
+func TestRunUnknownToolReturnsErrorResultButContinues(t *testing.T) {
+	gen := &scriptedGenerator{outputs: []string{
+		"```json\n{\"tool\": \"strumento_inesistente\", \"args\": {}}\n```",
+		"Risposta finale dopo l'errore dello strumento.",
+	}}
+
+	a := New(fakeRetriever{}, gen, 5)
+	answer, trace, err := a.Run(context.Background(), "domanda di prova")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if answer != "Risposta finale dopo l'errore dello strumento." {
+		t.Fatalf("answer = %q", answer)
+	}
+	if !strings.Contains(trace[0].ToolResult, "strumento sconosciuto") {
+		t.Fatalf("trace[0].ToolResult = %q, atteso errore di strumento sconosciuto", trace[0].ToolResult)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/gabrieledini/go-ollama3/agent"
+)
+
+// chatbotRetriever adatta RAGChatbot all'interfaccia agent.Retriever,
+// convertendo i Document del vector store nel tipo del pacchetto agent.
+type chatbotRetriever struct {
+	chatbot *RAGChatbot
+}
+
+func (a chatbotRetriever) SearchSimilar(query string, topK int) ([]agent.Document, error) {
+	docs, err := a.chatbot.SearchSimilar(query, topK)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]agent.Document, len(docs))
+	for i, d := range docs {
+		result[i] = agent.Document{ID: d.ID, Content: d.Content, Page: d.Page}
+	}
+	return result, nil
+}
+
+// addressablePage restituisce il numero di "pagina" con cui ListPages e
+// FetchPage indirizzano un Document. I formati paginati (PDF, capitoli
+// EPUB) hanno già un Page reale e lo usano direttamente; i formati senza
+// paginazione (TXT, Markdown, HTML, DOCX) lasciano Page a 0, quindi la
+// pagina viene derivata da Source+Heading così che list_pages/fetch_page
+// restino utilizzabili sezione per sezione invece di appiattire l'intero
+// file sotto un'unica pagina "0".
+func addressablePage(d Document) int {
+	if d.Page != 0 {
+		return d.Page
+	}
+	return syntheticPage(d.Source, d.Heading)
+}
+
+// syntheticPage deriva un numero di pagina negativo e deterministico da
+// Source e Heading, in modo da non collidere mai con le pagine reali
+// (sempre >= 1).
+func syntheticPage(source, heading string) int {
+	h := fnv.New32a()
+	h.Write([]byte(source + "|" + heading))
+	return -int(h.Sum32()%1_000_000) - 1
+}
+
+func (a chatbotRetriever) ListPages() []int {
+	seen := map[int]bool{}
+	var pages []int
+	for _, d := range a.chatbot.vectorStore.Documents {
+		page := addressablePage(d)
+		if !seen[page] {
+			seen[page] = true
+			pages = append(pages, page)
+		}
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+func (a chatbotRetriever) FetchPage(pageNumber int) (string, error) {
+	var b strings.Builder
+	found := false
+	for _, d := range a.chatbot.vectorStore.Documents {
+		if addressablePage(d) == pageNumber {
+			found = true
+			b.WriteString(d.Content)
+			b.WriteString("\n")
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("pagina %d non trovata", pageNumber)
+	}
+	return b.String(), nil
+}
+
+// chatbotGenerator adatta il backend di chat configurato all'interfaccia
+// agent.Generator, usando gli stessi parametri di campionamento già usati
+// da GenerateResponse.
+type chatbotGenerator struct {
+	chatbot *RAGChatbot
+}
+
+func (g chatbotGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	return g.chatbot.chatBackend.Generate(ctx, prompt, g.chatbot.genOpts)
+}
+
+// ChatWithAgent risponde alla domanda tramite il loop tool-calling invece
+// della pipeline one-shot, per le domande multi-hop che richiedono più
+// recuperi mirati in sequenza.
+func (r *RAGChatbot) ChatWithAgent(question string, maxSteps int) (string, []agent.Step, error) {
+	ag := agent.New(chatbotRetriever{chatbot: r}, chatbotGenerator{chatbot: r}, maxSteps)
+	return ag.Run(context.Background(), question)
+}
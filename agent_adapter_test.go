@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestChatbotRetrieverListAndFetchPageForNonPaginatedDocs(t *testing.T) {
+	chatbot := &RAGChatbot{
+		vectorStore: &VectorStore{
+			Documents: []Document{
+				{ID: "a1", Content: "primo paragrafo", Source: "note.md", Heading: "Introduzione"},
+				{ID: "a2", Content: "secondo paragrafo", Source: "note.md", Heading: "Introduzione"},
+				{ID: "b1", Content: "altra sezione", Source: "note.md", Heading: "Conclusioni"},
+			},
+		},
+	}
+	retriever := chatbotRetriever{chatbot: chatbot}
+
+	pages := retriever.ListPages()
+	if len(pages) != 2 {
+		t.Fatalf("ListPages() = %v, attese 2 pagine sintetiche distinte per Heading", pages)
+	}
+
+	content, err := retriever.FetchPage(pages[0])
+	if err != nil {
+		t.Fatalf("FetchPage: %v", err)
+	}
+	if content == "primo paragrafo\nsecondo paragrafo\naltra sezione\n" {
+		t.Fatalf("FetchPage ha concatenato l'intero file invece della sola sezione corrispondente")
+	}
+}
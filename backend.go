@@ -0,0 +1,597 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GenOpts raccoglie i parametri di campionamento comuni a tutti i backend di chat.
+type GenOpts struct {
+	Temperature float64
+	TopK        int
+	TopP        float64
+}
+
+// Token è un frammento di testo emesso durante la generazione in streaming.
+type Token struct {
+	Content string
+	Done    bool
+}
+
+// ChatBackend astrae il provider usato per generare risposte testuali,
+// così da poter mescolare Ollama locale con provider hosted (OpenAI,
+// Anthropic, Google) senza toccare la pipeline RAG.
+type ChatBackend interface {
+	Generate(ctx context.Context, prompt string, opts GenOpts) (string, error)
+	GenerateStream(ctx context.Context, prompt string, opts GenOpts) (<-chan Token, error)
+	Ping(ctx context.Context) error
+}
+
+// EmbeddingBackend astrae il provider usato per calcolare gli embedding.
+type EmbeddingBackend interface {
+	Embed(ctx context.Context, text string) ([]float64, error)
+}
+
+// OllamaBackend parla con un'istanza Ollama locale via HTTP.
+type OllamaBackend struct {
+	BaseURL string
+	Model   string
+}
+
+func (b *OllamaBackend) Generate(ctx context.Context, prompt string, opts GenOpts) (string, error) {
+	reqBody := OllamaRequest3T{
+		Model:       b.Model,
+		Prompt:      prompt,
+		Stream:      false,
+		Temperature: opts.Temperature,
+		TopK:        opts.TopK,
+		TopP:        opts.TopP,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("errore chiamata Ollama: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response OllamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+
+	return response.Response, nil
+}
+
+// GenerateStream apre una richiesta con Stream:true e legge lo stream
+// NDJSON di Ollama riga per riga, emettendo un Token per ogni oggetto
+// JSON ricevuto fino a quello con "done": true.
+func (b *OllamaBackend) GenerateStream(ctx context.Context, prompt string, opts GenOpts) (<-chan Token, error) {
+	reqBody := OllamaRequest3T{
+		Model:       b.Model,
+		Prompt:      prompt,
+		Stream:      true,
+		Temperature: opts.Temperature,
+		TopK:        opts.TopK,
+		TopP:        opts.TopP,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("errore chiamata Ollama: %v", err)
+	}
+
+	ch := make(chan Token)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+
+			var chunk OllamaResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				ch <- Token{Content: fmt.Sprintf("errore parsing stream: %v", err), Done: true}
+				return
+			}
+
+			ch <- Token{Content: chunk.Response, Done: chunk.Done}
+			if chunk.Done {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			ch <- Token{Content: fmt.Sprintf("errore lettura stream: %v", err), Done: true}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (b *OllamaBackend) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Ollama non disponibile su %s: %v", b.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Ollama risponde con status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// OllamaEmbedBackend calcola embedding tramite Ollama locale.
+type OllamaEmbedBackend struct {
+	BaseURL string
+	Model   string
+}
+
+func (b *OllamaEmbedBackend) Embed(ctx context.Context, text string) ([]float64, error) {
+	reqBody := EmbeddingRequest{
+		Model: b.Model,
+		Input: text,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+"/api/embed", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("errore chiamata Ollama embed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var embedResp EmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, err
+	}
+
+	if len(embedResp.Embeddings) == 0 {
+		return nil, fmt.Errorf("nessun embedding ricevuto")
+	}
+
+	return embedResp.Embeddings[0], nil
+}
+
+// OpenAIBackend parla con l'API Chat Completions di OpenAI.
+type OpenAIBackend struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+type openAIChatRequest struct {
+	Model       string              `json:"model"`
+	Messages    []openAIChatMessage `json:"messages"`
+	Temperature float64             `json:"temperature,omitempty"`
+	TopP        float64             `json:"top_p,omitempty"`
+}
+
+type openAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (b *OpenAIBackend) Generate(ctx context.Context, prompt string, opts GenOpts) (string, error) {
+	reqBody := openAIChatRequest{
+		Model:       b.Model,
+		Messages:    []openAIChatMessage{{Role: "user", Content: prompt}},
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+"/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.APIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("errore chiamata OpenAI: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("nessuna risposta ricevuta da OpenAI")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+func (b *OpenAIBackend) GenerateStream(ctx context.Context, prompt string, opts GenOpts) (<-chan Token, error) {
+	return singleShotStream(func() (string, error) {
+		return b.Generate(ctx, prompt, opts)
+	})
+}
+
+func (b *OpenAIBackend) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.BaseURL+"/v1/models", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.APIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("OpenAI non disponibile: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("OpenAI risponde con status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// OpenAIEmbedBackend calcola embedding tramite l'API Embeddings di OpenAI.
+type OpenAIEmbedBackend struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+type openAIEmbedRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+type openAIEmbedResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (b *OpenAIEmbedBackend) Embed(ctx context.Context, text string) ([]float64, error) {
+	reqBody := openAIEmbedRequest{Model: b.Model, Input: text}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+"/v1/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.APIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("errore chiamata OpenAI embed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response openAIEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("nessun embedding ricevuto da OpenAI")
+	}
+
+	return response.Data[0].Embedding, nil
+}
+
+// AnthropicBackend parla con l'API Messages di Anthropic.
+type AnthropicBackend struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+type anthropicRequest struct {
+	Model       string              `json:"model"`
+	MaxTokens   int                 `json:"max_tokens"`
+	Messages    []openAIChatMessage `json:"messages"`
+	Temperature float64             `json:"temperature,omitempty"`
+	TopP        float64             `json:"top_p,omitempty"`
+	TopK        int                 `json:"top_k,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+func (b *AnthropicBackend) Generate(ctx context.Context, prompt string, opts GenOpts) (string, error) {
+	reqBody := anthropicRequest{
+		Model:       b.Model,
+		MaxTokens:   4096,
+		Messages:    []openAIChatMessage{{Role: "user", Content: prompt}},
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		TopK:        opts.TopK,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+"/v1/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", b.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("errore chiamata Anthropic: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+
+	if len(response.Content) == 0 {
+		return "", fmt.Errorf("nessuna risposta ricevuta da Anthropic")
+	}
+
+	return response.Content[0].Text, nil
+}
+
+func (b *AnthropicBackend) GenerateStream(ctx context.Context, prompt string, opts GenOpts) (<-chan Token, error) {
+	return singleShotStream(func() (string, error) {
+		return b.Generate(ctx, prompt, opts)
+	})
+}
+
+func (b *AnthropicBackend) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.BaseURL+"/v1/models", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-api-key", b.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Anthropic non disponibile: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Anthropic risponde con status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GoogleBackend parla con l'API Generative Language (Gemini) di Google.
+type GoogleBackend struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+type googleGenerateRequest struct {
+	Contents         []googleContent         `json:"contents"`
+	GenerationConfig *googleGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type googleGenerationConfig struct {
+	Temperature float64 `json:"temperature,omitempty"`
+	TopK        int     `json:"topK,omitempty"`
+	TopP        float64 `json:"topP,omitempty"`
+}
+
+type googleContent struct {
+	Parts []googlePart `json:"parts"`
+}
+
+type googlePart struct {
+	Text string `json:"text"`
+}
+
+type googleGenerateResponse struct {
+	Candidates []struct {
+		Content googleContent `json:"content"`
+	} `json:"candidates"`
+}
+
+func (b *GoogleBackend) Generate(ctx context.Context, prompt string, opts GenOpts) (string, error) {
+	reqBody := googleGenerateRequest{
+		Contents: []googleContent{{Parts: []googlePart{{Text: prompt}}}},
+		GenerationConfig: &googleGenerationConfig{
+			Temperature: opts.Temperature,
+			TopK:        opts.TopK,
+			TopP:        opts.TopP,
+		},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", b.BaseURL, b.Model, b.APIKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("errore chiamata Google: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response googleGenerateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+
+	if len(response.Candidates) == 0 || len(response.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("nessuna risposta ricevuta da Google")
+	}
+
+	return response.Candidates[0].Content.Parts[0].Text, nil
+}
+
+func (b *GoogleBackend) GenerateStream(ctx context.Context, prompt string, opts GenOpts) (<-chan Token, error) {
+	return singleShotStream(func() (string, error) {
+		return b.Generate(ctx, prompt, opts)
+	})
+}
+
+func (b *GoogleBackend) Ping(ctx context.Context) error {
+	url := fmt.Sprintf("%s/v1beta/models?key=%s", b.BaseURL, b.APIKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Google non disponibile: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Google risponde con status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GoogleEmbedBackend calcola embedding tramite l'API Generative Language di Google.
+type GoogleEmbedBackend struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+type googleEmbedRequest struct {
+	Content googleContent `json:"content"`
+}
+
+type googleEmbedResponse struct {
+	Embedding struct {
+		Values []float64 `json:"values"`
+	} `json:"embedding"`
+}
+
+func (b *GoogleEmbedBackend) Embed(ctx context.Context, text string) ([]float64, error) {
+	reqBody := googleEmbedRequest{Content: googleContent{Parts: []googlePart{{Text: text}}}}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/v1beta/models/%s:embedContent?key=%s", b.BaseURL, b.Model, b.APIKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("errore chiamata Google embed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response googleEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	if len(response.Embedding.Values) == 0 {
+		return nil, fmt.Errorf("nessun embedding ricevuto da Google")
+	}
+
+	return response.Embedding.Values, nil
+}
+
+// singleShotStream adatta una chiamata non-streaming a un canale di Token,
+// utile per i backend che non espongono ancora un vero streaming NDJSON.
+func singleShotStream(call func() (string, error)) (<-chan Token, error) {
+	ch := make(chan Token, 1)
+
+	go func() {
+		defer close(ch)
+
+		text, err := call()
+		if err != nil {
+			ch <- Token{Content: fmt.Sprintf("errore: %v", err), Done: true}
+			return
+		}
+
+		ch <- Token{Content: text, Done: true}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// italianStopwords sono le parole funzionali italiane più comuni, escluse
+// dall'indicizzazione BM25 perché non discriminano tra i documenti.
+var italianStopwords = map[string]bool{
+	"il": true, "lo": true, "la": true, "i": true, "gli": true, "le": true,
+	"un": true, "uno": true, "una": true, "di": true, "a": true, "da": true,
+	"in": true, "con": true, "su": true, "per": true, "tra": true, "fra": true,
+	"e": true, "ed": true, "o": true, "ma": true, "che": true, "chi": true,
+	"cui": true, "non": true, "si": true, "se": true, "come": true, "più": true,
+	"anche": true, "questo": true, "questa": true, "questi": true, "queste": true,
+	"quello": true, "quella": true, "quelli": true, "quelle": true,
+	"sono": true, "è": true, "era": true, "essere": true, "al": true, "alla": true,
+	"del": true, "della": true, "dei": true, "delle": true, "nel": true, "nella": true,
+	"dal": true, "dalla": true, "sul": true, "sulla": true,
+}
+
+var tokenPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// italianSuffixes è un elenco grezzo di suffissi flessivi italiani più
+// comuni (plurali, femminili, avverbi), rimossi con lo stesso approccio
+// "strip the longest matching suffix" di uno stemmer leggero.
+var italianSuffixes = []string{
+	"izzazione", "mente", "issimo", "issima", "azione", "amento", "imento",
+	"anti", "enti", "ando", "endo", "ità", "ibile", "abile", "oso", "osa",
+	"ismo", "ista", "tore", "trice", "zione", "anza", "enza",
+	"ie", "he", "hi", "i", "e", "a", "o",
+}
+
+// stemItalian applica uno stemming leggero rimuovendo il suffisso
+// flessivo più lungo riconosciuto, mantenendo una radice di almeno 4
+// caratteri per evitare di accorciare troppo le parole brevi.
+func stemItalian(word string) string {
+	for _, suffix := range italianSuffixes {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 4 {
+			return word[:len(word)-len(suffix)]
+		}
+	}
+	return word
+}
+
+// tokenizeItalian normalizza, tokenizza, rimuove le stopword e applica lo
+// stemming leggero: la stessa pipeline viene usata sia per indicizzare i
+// chunk sia per tokenizzare la query, così i termini si confrontano alla
+// pari.
+func tokenizeItalian(text string) []string {
+	lower := strings.ToLower(text)
+	raw := tokenPattern.FindAllString(lower, -1)
+
+	tokens := make([]string, 0, len(raw))
+	for _, t := range raw {
+		if italianStopwords[t] {
+			continue
+		}
+		tokens = append(tokens, stemItalian(t))
+	}
+
+	return tokens
+}
+
+// BM25Index è un indice sparso a termine costruito sugli stessi chunk del
+// vector store, usato per catturare corrispondenze esatte (nomi propri,
+// codici, numeri) che la similarità coseno può perdere.
+type BM25Index struct {
+	documents  []Document
+	termFreqs  []map[string]int
+	docLengths []int
+	docFreq    map[string]int
+	avgDocLen  float64
+	k1         float64
+	b          float64
+}
+
+// NewBM25Index crea un indice BM25 vuoto con k1=1.5 e b=0.75.
+func NewBM25Index() *BM25Index {
+	return &BM25Index{
+		docFreq: map[string]int{},
+		k1:      1.5,
+		b:       0.75,
+	}
+}
+
+func (idx *BM25Index) Insert(doc Document) {
+	tokens := tokenizeItalian(doc.Content)
+
+	tf := map[string]int{}
+	seen := map[string]bool{}
+	for _, t := range tokens {
+		tf[t]++
+		if !seen[t] {
+			idx.docFreq[t]++
+			seen[t] = true
+		}
+	}
+
+	idx.documents = append(idx.documents, doc)
+	idx.termFreqs = append(idx.termFreqs, tf)
+	idx.docLengths = append(idx.docLengths, len(tokens))
+
+	total := 0
+	for _, l := range idx.docLengths {
+		total += l
+	}
+	idx.avgDocLen = float64(total) / float64(len(idx.docLengths))
+}
+
+// Rank restituisce tutti i documenti con punteggio BM25 positivo per la
+// query, ordinati per punteggio decrescente.
+func (idx *BM25Index) Rank(query string) []Document {
+	terms := tokenizeItalian(query)
+	n := float64(len(idx.documents))
+
+	type scored struct {
+		doc   Document
+		score float64
+	}
+
+	var scoredDocs []scored
+	for i, doc := range idx.documents {
+		var score float64
+		dl := float64(idx.docLengths[i])
+
+		for _, term := range terms {
+			tf := float64(idx.termFreqs[i][term])
+			if tf == 0 {
+				continue
+			}
+
+			df := float64(idx.docFreq[term])
+			idf := math.Log((n-df+0.5)/(df+0.5) + 1)
+			score += idf * (tf * (idx.k1 + 1)) / (tf + idx.k1*(1-idx.b+idx.b*dl/idx.avgDocLen))
+		}
+
+		if score > 0 {
+			scoredDocs = append(scoredDocs, scored{doc: doc, score: score})
+		}
+	}
+
+	sort.Slice(scoredDocs, func(i, j int) bool { return scoredDocs[i].score > scoredDocs[j].score })
+
+	results := make([]Document, len(scoredDocs))
+	for i, s := range scoredDocs {
+		results[i] = s.doc
+	}
+
+	return results
+}
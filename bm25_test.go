@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestBM25IndexRankPrefersExactTermMatch(t *testing.T) {
+	idx := NewBM25Index()
+	idx.Insert(Document{ID: "a", Content: "il gatto dorme sul divano"})
+	idx.Insert(Document{ID: "b", Content: "il cane corre nel parco"})
+
+	results := idx.Rank("gatto")
+	if len(results) != 1 || results[0].ID != "a" {
+		t.Fatalf("Rank(\"gatto\") = %v, atteso solo il documento a", results)
+	}
+}
+
+func TestBM25IndexRankNoMatchIsEmpty(t *testing.T) {
+	idx := NewBM25Index()
+	idx.Insert(Document{ID: "a", Content: "il gatto dorme sul divano"})
+
+	if results := idx.Rank("automobile"); len(results) != 0 {
+		t.Fatalf("Rank() con nessun termine in comune = %v, attesi zero risultati", results)
+	}
+}
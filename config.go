@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// BackendConfig descrive quale provider usare per una delle due fasi
+// della pipeline (chat o embedding) e le credenziali/parametri associati.
+// Viene popolato da variabili d'ambiente così da poter mescolare provider
+// diversi (es. embedding locale su Ollama, chat su un provider hosted)
+// senza dover ricompilare il binario.
+type BackendConfig struct {
+	Provider    string
+	BaseURL     string
+	APIKey      string
+	Model       string
+	Temperature float64
+	TopK        int
+	TopP        float64
+}
+
+// LoadChatBackendConfig legge la configurazione del backend di chat
+// dalle variabili d'ambiente, con fallback su Ollama locale. I parametri
+// di campionamento hanno come default gli stessi valori usati finora
+// (temperatura bassa per risposte precise e consistenti), ma diventano
+// regolabili senza ricompilare tramite CHAT_TEMPERATURE, CHAT_TOP_K e
+// CHAT_TOP_P.
+func LoadChatBackendConfig() BackendConfig {
+	return BackendConfig{
+		Provider:    envOrDefault("CHAT_BACKEND", "ollama"),
+		BaseURL:     envOrDefault("CHAT_BASE_URL", defaultBaseURL(envOrDefault("CHAT_BACKEND", "ollama"))),
+		APIKey:      os.Getenv("CHAT_API_KEY"),
+		Model:       envOrDefault("CHAT_MODEL", ""),
+		Temperature: envFloatOrDefault("CHAT_TEMPERATURE", 0.2),
+		TopK:        envIntOrDefault("CHAT_TOP_K", 40),
+		TopP:        envFloatOrDefault("CHAT_TOP_P", 0.9),
+	}
+}
+
+// LoadEmbedBackendConfig legge la configurazione del backend di embedding
+// dalle variabili d'ambiente, con fallback su Ollama locale.
+func LoadEmbedBackendConfig() BackendConfig {
+	return BackendConfig{
+		Provider: envOrDefault("EMBED_BACKEND", "ollama"),
+		BaseURL:  envOrDefault("EMBED_BASE_URL", defaultBaseURL(envOrDefault("EMBED_BACKEND", "ollama"))),
+		APIKey:   os.Getenv("EMBED_API_KEY"),
+		Model:    envOrDefault("EMBED_MODEL", "nomic-embed-text"),
+	}
+}
+
+func defaultBaseURL(provider string) string {
+	switch provider {
+	case "openai":
+		return "https://api.openai.com"
+	case "anthropic":
+		return "https://api.anthropic.com"
+	case "google":
+		return "https://generativelanguage.googleapis.com"
+	default:
+		return "http://localhost:11434"
+	}
+}
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envFloatOrDefault(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+func envIntOrDefault(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// NewChatBackend costruisce il ChatBackend indicato dalla configurazione.
+func NewChatBackend(cfg BackendConfig) (ChatBackend, error) {
+	switch cfg.Provider {
+	case "ollama":
+		return &OllamaBackend{BaseURL: cfg.BaseURL, Model: cfg.Model}, nil
+	case "openai":
+		return &OpenAIBackend{BaseURL: cfg.BaseURL, APIKey: cfg.APIKey, Model: cfg.Model}, nil
+	case "anthropic":
+		return &AnthropicBackend{BaseURL: cfg.BaseURL, APIKey: cfg.APIKey, Model: cfg.Model}, nil
+	case "google":
+		return &GoogleBackend{BaseURL: cfg.BaseURL, APIKey: cfg.APIKey, Model: cfg.Model}, nil
+	default:
+		return nil, unsupportedBackendError("chat", cfg.Provider)
+	}
+}
+
+// NewEmbeddingBackend costruisce l'EmbeddingBackend indicato dalla configurazione.
+func NewEmbeddingBackend(cfg BackendConfig) (EmbeddingBackend, error) {
+	switch cfg.Provider {
+	case "ollama":
+		return &OllamaEmbedBackend{BaseURL: cfg.BaseURL, Model: cfg.Model}, nil
+	case "openai":
+		return &OpenAIEmbedBackend{BaseURL: cfg.BaseURL, APIKey: cfg.APIKey, Model: cfg.Model}, nil
+	case "google":
+		return &GoogleEmbedBackend{BaseURL: cfg.BaseURL, APIKey: cfg.APIKey, Model: cfg.Model}, nil
+	default:
+		return nil, unsupportedBackendError("embedding", cfg.Provider)
+	}
+}
+
+func unsupportedBackendError(kind, provider string) error {
+	return &unsupportedBackendErr{kind: kind, provider: provider}
+}
+
+type unsupportedBackendErr struct {
+	kind     string
+	provider string
+}
+
+func (e *unsupportedBackendErr) Error() string {
+	return "provider " + e.kind + " non supportato: " + e.provider
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message è il contenuto di un turno di conversazione: una domanda
+// dell'utente o una risposta del modello.
+type Message struct {
+	Role      string   `json:"role"` // "user" o "assistant"
+	Content   string   `json:"content"`
+	SourceIDs []string `json:"source_ids,omitempty"` // ID dei Document usati come fonte
+}
+
+// MessageNode è un nodo dell'albero dei messaggi: porta il proprio messaggio
+// più il riferimento al genitore, così che modificare un messaggio passato
+// crei un branch fratello invece di sovrascrivere la storia.
+type MessageNode struct {
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	ChildIDs  []string  `json:"child_ids,omitempty"`
+	Message   Message   `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Conversation è un albero di MessageNode con un puntatore all'ultimo
+// nodo attivo (HeadID), cioè il branch seguito di default da "reply".
+type Conversation struct {
+	ID     string                  `json:"id"`
+	Title  string                  `json:"title"`
+	Nodes  map[string]*MessageNode `json:"nodes"`
+	RootID string                  `json:"root_id,omitempty"`
+	HeadID string                  `json:"head_id,omitempty"`
+}
+
+// ConversationStore persiste tutte le conversazioni in un file JSON
+// accanto al vector store.
+type ConversationStore struct {
+	Conversations map[string]*Conversation `json:"conversations"`
+	path          string
+}
+
+// NewConversationStore crea uno store vuoto che salva su path.
+func NewConversationStore(path string) *ConversationStore {
+	return &ConversationStore{
+		Conversations: map[string]*Conversation{},
+		path:          path,
+	}
+}
+
+// Save scrive lo store su disco.
+func (s *ConversationStore) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// Load carica lo store da disco, se esiste.
+func (s *ConversationStore) Load() error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return fmt.Errorf("archivio conversazioni non esistente")
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, s)
+}
+
+// NewConversation crea e registra una nuova conversazione vuota.
+func (s *ConversationStore) NewConversation(title string) *Conversation {
+	conv := &Conversation{
+		ID:    newID("conv"),
+		Title: title,
+		Nodes: map[string]*MessageNode{},
+	}
+
+	s.Conversations[conv.ID] = conv
+	return conv
+}
+
+// AddMessage aggiunge un nodo figlio di parentID (o una nuova radice se
+// parentID è vuoto) e lo rende il nuovo head della conversazione.
+func (c *Conversation) AddMessage(parentID string, msg Message) *MessageNode {
+	node := &MessageNode{
+		ID:        newID("msg"),
+		ParentID:  parentID,
+		Message:   msg,
+		CreatedAt: time.Now(),
+	}
+
+	c.Nodes[node.ID] = node
+
+	if parentID == "" {
+		if c.RootID == "" {
+			c.RootID = node.ID
+		}
+	} else if parent, ok := c.Nodes[parentID]; ok {
+		parent.ChildIDs = append(parent.ChildIDs, node.ID)
+	}
+
+	c.HeadID = node.ID
+	return node
+}
+
+// EditMessage crea un branch fratello del nodo indicato con un nuovo
+// contenuto: la storia originale resta intatta, il nuovo nodo diventa
+// il nuovo head così che "reply" prosegua dal branch appena creato.
+func (c *Conversation) EditMessage(nodeID string, newContent string) (*MessageNode, error) {
+	original, ok := c.Nodes[nodeID]
+	if !ok {
+		return nil, fmt.Errorf("messaggio %s non trovato", nodeID)
+	}
+
+	edited := Message{Role: original.Message.Role, Content: newContent}
+	sibling := c.AddMessage(original.ParentID, edited)
+	return sibling, nil
+}
+
+// Checkout sposta l'head su un nodo già esistente della conversazione
+// (tipicamente uno degli ID stampati da Branches) senza toccarne il
+// contenuto: è il modo puro di cambiare branch attivo, a differenza di
+// EditMessage che ne crea sempre uno nuovo.
+func (c *Conversation) Checkout(nodeID string) error {
+	if _, ok := c.Nodes[nodeID]; !ok {
+		return fmt.Errorf("messaggio %s non trovato", nodeID)
+	}
+
+	c.HeadID = nodeID
+	return nil
+}
+
+// Branches restituisce gli ID dei nodi figli di nodeID, cioè i branch
+// disponibili a partire da quel punto della conversazione.
+func (c *Conversation) Branches(nodeID string) []string {
+	node, ok := c.Nodes[nodeID]
+	if !ok {
+		return nil
+	}
+
+	return node.ChildIDs
+}
+
+// PathToHead ricostruisce, dalla radice fino all'head corrente, la
+// sequenza di nodi del branch attivo.
+func (c *Conversation) PathToHead() []*MessageNode {
+	return c.PathTo(c.HeadID)
+}
+
+// PathTo ricostruisce la sequenza di nodi dalla radice fino a nodeID,
+// seguendo i ParentID a ritroso.
+func (c *Conversation) PathTo(nodeID string) []*MessageNode {
+	var path []*MessageNode
+
+	for id := nodeID; id != ""; {
+		node, ok := c.Nodes[id]
+		if !ok {
+			break
+		}
+		path = append([]*MessageNode{node}, path...)
+		id = node.ParentID
+	}
+
+	return path
+}
+
+// newID genera un identificativo breve e leggibile nello stile già usato
+// per gli ID dei chunk (prefisso + hash troncato).
+func newID(prefix string) string {
+	hasher := md5.New()
+	hasher.Write([]byte(fmt.Sprintf("%s-%d-%d", prefix, time.Now().UnixNano(), len(prefix))))
+	return fmt.Sprintf("%s_%x", prefix, hasher.Sum(nil)[:4])
+}
+
+// previewContent accorcia un contenuto lungo per la stampa a schermo.
+func previewContent(content string, maxLen int) string {
+	content = strings.TrimSpace(content)
+	if len(content) > maxLen {
+		return content[:maxLen] + "..."
+	}
+	return content
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestConversationCheckoutSwitchesHeadWithoutNewNode(t *testing.T) {
+	store := NewConversationStore("")
+	conv := store.NewConversation("test")
+
+	root := conv.AddMessage("", Message{Role: "user", Content: "domanda"})
+	branchA := conv.AddMessage(root.ID, Message{Role: "assistant", Content: "risposta A"})
+	branchB, err := conv.EditMessage(branchA.ID, "risposta B")
+	if err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+
+	nodeCount := len(conv.Nodes)
+
+	if err := conv.Checkout(branchA.ID); err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	if conv.HeadID != branchA.ID {
+		t.Fatalf("HeadID = %s, atteso %s", conv.HeadID, branchA.ID)
+	}
+	if len(conv.Nodes) != nodeCount {
+		t.Fatalf("Checkout ha creato %d nodi, non dovrebbe crearne nessuno", len(conv.Nodes)-nodeCount)
+	}
+
+	if err := conv.Checkout("inesistente"); err == nil {
+		t.Fatalf("Checkout su un ID inesistente doveva fallire")
+	}
+	if conv.HeadID != branchA.ID {
+		t.Fatalf("un Checkout fallito non dovrebbe spostare l'head (rimasto %s)", conv.HeadID)
+	}
+
+	_ = branchB
+}
+
+func TestEditMessageOnRootKeepsOriginalRootID(t *testing.T) {
+	store := NewConversationStore("")
+	conv := store.NewConversation("test")
+
+	root := conv.AddMessage("", Message{Role: "user", Content: "domanda originale"})
+	if conv.RootID != root.ID {
+		t.Fatalf("RootID = %s, atteso %s", conv.RootID, root.ID)
+	}
+
+	editedRoot, err := conv.EditMessage(root.ID, "domanda modificata")
+	if err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+
+	if conv.RootID != root.ID {
+		t.Fatalf("RootID = %s dopo EditMessage sulla radice, atteso che restasse %s", conv.RootID, root.ID)
+	}
+	if _, ok := conv.Nodes[root.ID]; !ok {
+		t.Fatalf("il nodo radice originale %s non dovrebbe sparire da Nodes", root.ID)
+	}
+	if editedRoot.ParentID != "" {
+		t.Fatalf("ParentID del branch modificato = %q, atteso vuoto (è anch'esso una radice)", editedRoot.ParentID)
+	}
+}
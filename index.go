@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// Index astrae la struttura usata per trovare i Document più simili a un
+// vettore di query, così da poter sostituire la scansione lineare con
+// un'approssimazione più scalabile senza toccare SearchSimilar.
+type Index interface {
+	Insert(doc Document)
+	Search(queryVector []float64, topK int) []Document
+}
+
+// FlatIndex replica il comportamento originale: scansione lineare di
+// tutti i documenti con ordinamento per similarità coseno decrescente.
+// Resta la scelta corretta sotto qualche migliaio di chunk.
+type FlatIndex struct {
+	documents []Document
+}
+
+// NewFlatIndex crea un FlatIndex vuoto.
+func NewFlatIndex() *FlatIndex {
+	return &FlatIndex{}
+}
+
+func (f *FlatIndex) Insert(doc Document) {
+	f.documents = append(f.documents, doc)
+}
+
+func (f *FlatIndex) Search(queryVector []float64, topK int) []Document {
+	type scored struct {
+		doc   Document
+		score float64
+	}
+
+	scoredDocs := make([]scored, len(f.documents))
+	for i, doc := range f.documents {
+		scoredDocs[i] = scored{doc: doc, score: cosineSimilarity(queryVector, doc.Vector)}
+	}
+
+	sort.Slice(scoredDocs, func(i, j int) bool {
+		return scoredDocs[i].score > scoredDocs[j].score
+	})
+
+	if topK > len(scoredDocs) {
+		topK = len(scoredDocs)
+	}
+
+	results := make([]Document, topK)
+	for i := 0; i < topK; i++ {
+		results[i] = scoredDocs[i].doc
+	}
+
+	return results
+}
+
+// hnswNode è un nodo del grafo di prossimità multi-livello: il documento,
+// il livello più alto a cui compare, e la lista di vicini per ogni livello
+// da 0 (il più denso) fino al suo Level.
+type hnswNode struct {
+	Doc       Document   `json:"doc"`
+	Level     int        `json:"level"`
+	Neighbors [][]string `json:"neighbors"`
+}
+
+// HNSWIndex implementa Hierarchical Navigable Small World: un inserimento
+// sceglie un livello casuale, scende a greedy fino a quel livello+1 per
+// trovare il punto d'ingresso migliore, poi da quel livello a 0 esegue una
+// beam search di ampiezza EfConstruction per collegare il nuovo nodo agli M
+// vicini più vicini (potando anche le loro liste di adiacenza a M). Le
+// query scendono allo stesso modo fino al layer 0 ed eseguono lì una beam
+// search di ampiezza EfSearch.
+type HNSWIndex struct {
+	Nodes          map[string]*hnswNode `json:"nodes"`
+	EntryPoint     string               `json:"entry_point"`
+	MaxLevel       int                  `json:"max_level"`
+	ModelName      string               `json:"model_name"`
+	M              int                  `json:"m"`
+	EfConstruction int                  `json:"ef_construction"`
+	EfSearch       int                  `json:"ef_search"`
+	mL             float64
+	rng            *rand.Rand
+}
+
+// NewHNSWIndex crea un indice HNSW vuoto con i parametri di default
+// suggeriti dalla letteratura: M=16, efConstruction=200, efSearch=50,
+// mL=1/ln(M).
+func NewHNSWIndex() *HNSWIndex {
+	const m = 16
+	return &HNSWIndex{
+		Nodes:          map[string]*hnswNode{},
+		M:              m,
+		EfConstruction: 200,
+		EfSearch:       50,
+		mL:             mLForM(m),
+		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// mLForM calcola 1/ln(M), il fattore che governa la distribuzione
+// esponenziale dei livelli assegnati in Insert. M<2 non avrebbe un
+// logaritmo utilizzabile (ln(1)=0, ln(<1)<0), quindi ricade sul default
+// di NewHNSWIndex.
+func mLForM(m int) float64 {
+	if m < 2 {
+		m = 16
+	}
+	return 1 / math.Log(float64(m))
+}
+
+type hnswCandidate struct {
+	id   string
+	dist float64
+}
+
+func vectorDistance(a, b []float64) float64 {
+	return 1 - cosineSimilarity(a, b)
+}
+
+func (h *HNSWIndex) Insert(doc Document) {
+	if h.rng == nil {
+		h.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	level := int(math.Floor(-math.Log(h.rng.Float64()) * h.mL))
+	node := &hnswNode{Doc: doc, Level: level, Neighbors: make([][]string, level+1)}
+	h.Nodes[doc.ID] = node
+
+	if h.EntryPoint == "" {
+		h.EntryPoint = doc.ID
+		h.MaxLevel = level
+		return
+	}
+
+	current := h.EntryPoint
+	for lc := h.MaxLevel; lc > level; lc-- {
+		current = h.greedyClosest(current, doc.Vector, lc)
+	}
+
+	for lc := minInt(level, h.MaxLevel); lc >= 0; lc-- {
+		candidates := h.searchLayer(current, doc.Vector, h.EfConstruction, lc)
+		neighbors := selectClosest(candidates, h.M)
+
+		ids := make([]string, len(neighbors))
+		for i, n := range neighbors {
+			ids[i] = n.id
+		}
+		node.Neighbors[lc] = ids
+
+		for _, n := range neighbors {
+			neighborNode := h.Nodes[n.id]
+			neighborNode.Neighbors[lc] = append(neighborNode.Neighbors[lc], doc.ID)
+			h.pruneNeighbors(neighborNode, lc)
+		}
+
+		if len(candidates) > 0 {
+			current = candidates[0].id
+		}
+	}
+
+	if level > h.MaxLevel {
+		h.MaxLevel = level
+		h.EntryPoint = doc.ID
+	}
+}
+
+func (h *HNSWIndex) Search(queryVector []float64, topK int) []Document {
+	if h.EntryPoint == "" {
+		return nil
+	}
+
+	current := h.EntryPoint
+	for lc := h.MaxLevel; lc > 0; lc-- {
+		current = h.greedyClosest(current, queryVector, lc)
+	}
+
+	ef := h.EfSearch
+	if topK > ef {
+		ef = topK
+	}
+
+	candidates := h.searchLayer(current, queryVector, ef, 0)
+	if topK > len(candidates) {
+		topK = len(candidates)
+	}
+
+	results := make([]Document, topK)
+	for i := 0; i < topK; i++ {
+		results[i] = h.Nodes[candidates[i].id].Doc
+	}
+
+	return results
+}
+
+// greedyClosest scende a ogni passo verso il vicino più vicino al livello
+// lc finché non ci sono più miglioramenti: usato per la discesa dai
+// livelli alti, dove il grafo è rado e basta un singolo percorso greedy.
+func (h *HNSWIndex) greedyClosest(entryID string, queryVector []float64, lc int) string {
+	best := entryID
+	bestDist := vectorDistance(h.Nodes[best].Doc.Vector, queryVector)
+
+	for improved := true; improved; {
+		improved = false
+		node := h.Nodes[best]
+		if lc >= len(node.Neighbors) {
+			break
+		}
+
+		for _, neighborID := range node.Neighbors[lc] {
+			d := vectorDistance(h.Nodes[neighborID].Doc.Vector, queryVector)
+			if d < bestDist {
+				best, bestDist, improved = neighborID, d, true
+			}
+		}
+	}
+
+	return best
+}
+
+// searchLayer esegue una beam search di ampiezza ef sul livello lc a
+// partire da entryID, restituendo i candidati ordinati per distanza
+// crescente (il più vicino per primo).
+func (h *HNSWIndex) searchLayer(entryID string, queryVector []float64, ef int, lc int) []hnswCandidate {
+	entryDist := vectorDistance(h.Nodes[entryID].Doc.Vector, queryVector)
+	visited := map[string]bool{entryID: true}
+	candidates := []hnswCandidate{{id: entryID, dist: entryDist}}
+	results := []hnswCandidate{{id: entryID, dist: entryDist}}
+
+	for len(candidates) > 0 {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+		c := candidates[0]
+		candidates = candidates[1:]
+
+		sort.Slice(results, func(i, j int) bool { return results[i].dist < results[j].dist })
+		if len(results) >= ef && c.dist > results[len(results)-1].dist {
+			break
+		}
+
+		node := h.Nodes[c.id]
+		if lc >= len(node.Neighbors) {
+			continue
+		}
+
+		for _, neighborID := range node.Neighbors[lc] {
+			if visited[neighborID] {
+				continue
+			}
+			visited[neighborID] = true
+
+			d := vectorDistance(h.Nodes[neighborID].Doc.Vector, queryVector)
+			sort.Slice(results, func(i, j int) bool { return results[i].dist < results[j].dist })
+
+			if len(results) < ef || d < results[len(results)-1].dist {
+				candidates = append(candidates, hnswCandidate{id: neighborID, dist: d})
+				results = append(results, hnswCandidate{id: neighborID, dist: d})
+				if len(results) > ef {
+					sort.Slice(results, func(i, j int) bool { return results[i].dist < results[j].dist })
+					results = results[:ef]
+				}
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].dist < results[j].dist })
+	return results
+}
+
+func selectClosest(candidates []hnswCandidate, m int) []hnswCandidate {
+	if len(candidates) <= m {
+		return candidates
+	}
+	return candidates[:m]
+}
+
+// pruneNeighbors riporta la lista di adiacenza del nodo al livello lc a
+// non più di M elementi, tenendo i vicini più vicini.
+func (h *HNSWIndex) pruneNeighbors(node *hnswNode, lc int) {
+	ids := node.Neighbors[lc]
+	if len(ids) <= h.M {
+		return
+	}
+
+	cands := make([]hnswCandidate, len(ids))
+	for i, id := range ids {
+		cands[i] = hnswCandidate{id: id, dist: vectorDistance(h.Nodes[id].Doc.Vector, node.Doc.Vector)}
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].dist < cands[j].dist })
+
+	pruned := make([]string, h.M)
+	for i := 0; i < h.M; i++ {
+		pruned[i] = cands[i].id
+	}
+	node.Neighbors[lc] = pruned
+}
+
+// Save persiste il grafo (vettori, adiacenza, entry point e livello) su
+// disco accanto al vector store.
+func (h *HNSWIndex) Save(path string) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadHNSWIndex carica un indice HNSW serializzato da disco.
+func LoadHNSWIndex(path string) (*HNSWIndex, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("indice HNSW non esistente")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &HNSWIndex{}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, err
+	}
+	// mL non viene serializzato (è derivato da M): va ricalcolato dopo il
+	// reload, altrimenti resta a zero e ogni Insert successivo assegna
+	// sempre livello 0.
+	idx.mL = mLForM(idx.M)
+
+	return idx, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestHNSWIndexSaveLoadPreservesML(t *testing.T) {
+	idx := NewHNSWIndex()
+	for i := 0; i < 10; i++ {
+		idx.Insert(Document{
+			ID:     fmtID(i),
+			Vector: []float64{float64(i), float64(i + 1)},
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "hnsw.json")
+	if err := idx.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded, err := LoadHNSWIndex(path)
+	if err != nil {
+		t.Fatalf("LoadHNSWIndex: %v", err)
+	}
+
+	want := mLForM(idx.M)
+	if math.Abs(reloaded.mL-want) > 1e-9 {
+		t.Fatalf("mL dopo il reload = %v, atteso %v (M=%d)", reloaded.mL, want, reloaded.M)
+	}
+
+	// Un Insert su un indice ricaricato deve ancora andare a buon fine; il
+	// livello che assegna dipende da mL, già verificato sopra.
+	reloaded.Insert(Document{ID: "extra", Vector: []float64{1, 2}})
+	if reloaded.Nodes["extra"] == nil {
+		t.Fatalf("documento non inserito dopo il reload")
+	}
+}
+
+func fmtID(i int) string {
+	return "doc_" + string(rune('a'+i))
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var nonAlnumPattern = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// sanitizeID riduce un nome file a un prefisso sicuro da usare in un ID
+// di documento, sullo stesso principio degli ID "page_N_chunk_M" e
+// "txt_chunk_N" generati in origine da ProcessPDF e ProcessTXT.
+func sanitizeID(name string) string {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.Trim(nonAlnumPattern.ReplaceAllString(base, "_"), "_")
+}
+
+// ProcessFile instrada l'ingestione in base all'estensione del file verso
+// il Loader corretto (PDF, TXT, Markdown, HTML, DOCX o EPUB), sostituisce
+// il vector store con i Document risultanti e lo salva su disco. È la
+// pipeline comune dietro ProcessPDF e ProcessTXT, che restano alias per
+// compatibilità con il codice chiamante esistente.
+func (r *RAGChatbot) ProcessFile(path string) error {
+	loader, err := loaderFor(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📄 Estrazione testo da %s...\n", filepath.Base(path))
+	sections, err := loader.Load(path)
+	if err != nil {
+		return err
+	}
+
+	r.resetDocuments()
+
+	fmt.Println("🔤 Creazione chunks e embedding...")
+	added, err := r.appendSections(sections, filepath.Base(path), map[string]bool{})
+	if err != nil {
+		return err
+	}
+
+	r.vectorStore.ModelName = r.embedModel
+	fmt.Printf("\n✅ Creati %d chunks con embedding\n", added)
+
+	return r.SaveVectorStore()
+}
+
+// ProcessDirectory percorre root ricorsivamente e ingerisce ogni file con
+// estensione supportata nello stesso vector store, de-duplicando i chunk
+// per hash del contenuto (utile quando più file condividono paragrafi,
+// es. un'intestazione ripetuta). glob, se non vuoto, filtra i nomi file
+// con filepath.Match (es. "*.md"); vuoto significa "tutti i formati
+// supportati".
+func (r *RAGChatbot) ProcessDirectory(root string, glob string) error {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		if glob != "" {
+			matched, matchErr := filepath.Match(glob, d.Name())
+			if matchErr != nil {
+				return matchErr
+			}
+			if !matched {
+				return nil
+			}
+		}
+
+		if _, ok := loaderExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("errore scansione cartella: %v", err)
+	}
+
+	fmt.Printf("📁 Trovati %d file supportati\n", len(paths))
+
+	r.resetDocuments()
+
+	seenHashes := map[string]bool{}
+	totalChunks := 0
+
+	for _, path := range paths {
+		loader, err := loaderFor(path)
+		if err != nil {
+			log.Printf("Errore selezione loader per %s: %v", path, err)
+			continue
+		}
+
+		fmt.Printf("📄 Estrazione testo da %s...\n", filepath.Base(path))
+		sections, err := loader.Load(path)
+		if err != nil {
+			log.Printf("Errore estrazione da %s: %v", path, err)
+			continue
+		}
+
+		added, err := r.appendSections(sections, filepath.Base(path), seenHashes)
+		if err != nil {
+			log.Printf("Errore ingestione di %s: %v", path, err)
+			continue
+		}
+		totalChunks += added
+	}
+
+	r.vectorStore.ModelName = r.embedModel
+	fmt.Printf("\n✅ Creati %d chunks con embedding da %d file\n", totalChunks, len(paths))
+
+	return r.SaveVectorStore()
+}
+
+// resetDocuments svuota il vector store e invalida gli indici in memoria
+// prima di una nuova ingestione. Per l'HNSW rimuove anche il grafo
+// serializzato su disco: ensureIndex lo ricaricherebbe altrimenti perché
+// indexPath resta valido a parità di modello di embedding, servendo
+// risposte sul set di documenti precedente invece di quello appena
+// ingerito.
+func (r *RAGChatbot) resetDocuments() {
+	r.vectorStore.Documents = []Document{}
+	r.index = nil
+	r.bm25 = nil
+
+	if err := os.Remove(r.indexPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Attenzione: impossibile invalidare l'indice HNSW in cache: %v", err)
+	}
+}
+
+// appendSections suddivide in chunk ogni Section e ne genera l'embedding,
+// aggiungendo i Document risultanti al vector store. seenHashes tiene
+// traccia degli hash MD5 dei chunk già inseriti (condiviso tra più file
+// in ProcessDirectory) per scartare i duplicati di contenuto. Restituisce
+// il numero di chunk effettivamente aggiunti.
+func (r *RAGChatbot) appendSections(sections []Section, source string, seenHashes map[string]bool) (int, error) {
+	added := 0
+
+	for secIdx, sec := range sections {
+		chunks := r.ChunkText(sec.Content, 300, 50) // 300 parole per chunk, overlap 50
+
+		for chunkIdx, chunk := range chunks {
+			if len(strings.TrimSpace(chunk)) < 20 {
+				continue
+			}
+
+			hash := md5.Sum([]byte(chunk))
+			hashHex := fmt.Sprintf("%x", hash)
+			if seenHashes[hashHex] {
+				continue
+			}
+			seenHashes[hashHex] = true
+
+			docID := fmt.Sprintf("%s_sec%d_chunk%d_%s", sanitizeID(source), secIdx+1, chunkIdx, hashHex[:8])
+
+			fmt.Printf("🔄 Processando %s, sezione %d, chunk %d\r", source, secIdx+1, chunkIdx+1)
+
+			vector, err := r.GetEmbedding(chunk)
+			if err != nil {
+				log.Printf("Errore embedding per chunk %s: %v", docID, err)
+				continue
+			}
+
+			doc := Document{
+				ID:      docID,
+				Content: chunk,
+				Page:    sec.Page,
+				Source:  source,
+				Section: fmt.Sprintf("Sezione %d", secIdx+1),
+				Heading: sec.Heading,
+				Vector:  vector,
+			}
+
+			r.vectorStore.Documents = append(r.vectorStore.Documents, doc)
+			added++
+
+			// Pausa per non sovraccaricare Ollama
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	return added, nil
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Section è l'unità grezza prodotta da un Loader: un frammento di testo
+// con i metadati di provenienza necessari a costruire citazioni migliori
+// di "pagina 1" per i formati che non sono paginati come il PDF.
+type Section struct {
+	Content string
+	Page    int    // numero di pagina, 0 se il formato non è paginato
+	Heading string // titolo della sezione/capitolo, se il formato ne ha uno
+	Source  string // nome del file di provenienza
+}
+
+// Loader estrae le Section di testo grezzo da un file di un formato
+// specifico. ProcessFile sceglie l'implementazione in base all'estensione
+// e passa il risultato alla stessa pipeline di chunking ed embedding per
+// tutti i formati.
+type Loader interface {
+	Load(path string) ([]Section, error)
+}
+
+// loaderExtensions associa ogni estensione supportata al suo Loader,
+// usata sia da loaderFor sia da ProcessDirectory per filtrare i file da
+// ingerire.
+var loaderExtensions = map[string]Loader{
+	".pdf":  PDFLoader{},
+	".txt":  TXTLoader{},
+	".md":   MarkdownLoader{},
+	".html": HTMLLoader{},
+	".htm":  HTMLLoader{},
+	".docx": DocxLoader{},
+	".epub": EpubLoader{},
+}
+
+// loaderFor sceglie il Loader in base all'estensione del file.
+func loaderFor(path string) (Loader, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	loader, ok := loaderExtensions[ext]
+	if !ok {
+		return nil, fmt.Errorf("formato non supportato: %s", ext)
+	}
+	return loader, nil
+}
+
+// cleanText normalizza gli spazi e rimuove i caratteri non stampabili da
+// un testo estratto, indipendentemente dal formato di provenienza.
+func cleanText(text string) string {
+	reg := regexp.MustCompile(`\s+`)
+	text = reg.ReplaceAllString(text, " ")
+
+	reg = regexp.MustCompile(`[^\p{L}\p{N}\p{P}\p{Z}]+`)
+	text = reg.ReplaceAllString(text, " ")
+
+	return strings.TrimSpace(text)
+}
+
+// PDFLoader estrae una Section per pagina, come faceva in origine
+// ExtractTextFromPDF.
+type PDFLoader struct{}
+
+func (PDFLoader) Load(path string) ([]Section, error) {
+	file, reader, err := pdf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("errore apertura PDF: %v", err)
+	}
+	defer file.Close()
+
+	source := filepath.Base(path)
+	totalPages := reader.NumPage()
+	fmt.Printf("Elaborazione PDF: %d pagine trovate\n", totalPages)
+
+	var sections []Section
+	for pageNum := 1; pageNum <= totalPages; pageNum++ {
+		page := reader.Page(pageNum)
+		if page.V.IsNull() {
+			continue
+		}
+
+		content, err := page.GetPlainText(nil)
+		if err != nil {
+			log.Printf("Errore estrazione pagina %d: %v", pageNum, err)
+			continue
+		}
+
+		cleanContent := cleanText(content)
+		if len(cleanContent) > 50 { // Solo se ha contenuto significativo
+			sections = append(sections, Section{Content: cleanContent, Page: pageNum, Source: source})
+		}
+	}
+
+	return sections, nil
+}
+
+// TXTLoader restituisce l'intero file come un'unica Section non
+// paginata: per i file TXT non c'è un concetto naturale di pagina.
+type TXTLoader struct{}
+
+func (TXTLoader) Load(path string) ([]Section, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("errore lettura file TXT: %v", err)
+	}
+
+	cleaned := cleanText(string(content))
+	if len(strings.TrimSpace(cleaned)) < 50 {
+		return nil, fmt.Errorf("file TXT troppo corto o vuoto")
+	}
+
+	return []Section{{Content: cleaned, Source: filepath.Base(path)}}, nil
+}
+
+var markdownHeadingPattern = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+
+// MarkdownLoader divide il file in una Section per ogni heading (# ... ##
+// ...), così che Document.Heading rifletta la struttura del documento.
+// Il testo prima del primo heading diventa una Section senza titolo solo
+// se, ripulito, supera i 50 caratteri; altrimenti viene scartato.
+type MarkdownLoader struct{}
+
+func (MarkdownLoader) Load(path string) ([]Section, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("errore lettura file Markdown: %v", err)
+	}
+
+	source := filepath.Base(path)
+	text := string(content)
+
+	headingMatches := markdownHeadingPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(headingMatches) == 0 {
+		cleaned := cleanText(text)
+		if len(strings.TrimSpace(cleaned)) < 50 {
+			return nil, fmt.Errorf("file Markdown troppo corto o vuoto")
+		}
+		return []Section{{Content: cleaned, Source: source}}, nil
+	}
+
+	var sections []Section
+	if preamble := cleanText(text[:headingMatches[0][0]]); len(preamble) > 50 {
+		sections = append(sections, Section{Content: preamble, Source: source})
+	}
+
+	for i, match := range headingMatches {
+		heading := strings.TrimSpace(text[match[2]:match[3]])
+		bodyStart := match[1]
+		bodyEnd := len(text)
+		if i+1 < len(headingMatches) {
+			bodyEnd = headingMatches[i+1][0]
+		}
+
+		body := cleanText(text[bodyStart:bodyEnd])
+		if body == "" {
+			continue
+		}
+		sections = append(sections, Section{Content: body, Heading: heading, Source: source})
+	}
+
+	return sections, nil
+}
+
+var (
+	htmlHeadingPattern = regexp.MustCompile(`(?is)<h[1-6][^>]*>(.*?)</h[1-6]>`)
+	htmlScriptPattern  = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<[^>]+>`)
+)
+
+// stripHTMLTags rimuove tag, script e stili da un frammento HTML,
+// lasciando solo il testo visibile.
+func stripHTMLTags(html string) string {
+	html = htmlScriptPattern.ReplaceAllString(html, " ")
+	html = htmlTagPattern.ReplaceAllString(html, " ")
+	return cleanText(html)
+}
+
+// htmlToSections divide un documento HTML/XHTML in una Section per ogni
+// heading <h1>-<h6>, condivisa da HTMLLoader ed EpubLoader (i capitoli di
+// un EPUB sono file XHTML).
+func htmlToSections(html, source string, page int) []Section {
+	html = htmlScriptPattern.ReplaceAllString(html, " ")
+
+	headingMatches := htmlHeadingPattern.FindAllStringSubmatchIndex(html, -1)
+	if len(headingMatches) == 0 {
+		body := stripHTMLTags(html)
+		if len(body) < 20 {
+			return nil
+		}
+		return []Section{{Content: body, Source: source, Page: page}}
+	}
+
+	var sections []Section
+	if preamble := stripHTMLTags(html[:headingMatches[0][0]]); len(preamble) >= 20 {
+		sections = append(sections, Section{Content: preamble, Source: source, Page: page})
+	}
+
+	for i, match := range headingMatches {
+		heading := stripHTMLTags(html[match[2]:match[3]])
+		bodyStart := match[1]
+		bodyEnd := len(html)
+		if i+1 < len(headingMatches) {
+			bodyEnd = headingMatches[i+1][0]
+		}
+
+		body := stripHTMLTags(html[bodyStart:bodyEnd])
+		if body == "" {
+			continue
+		}
+		sections = append(sections, Section{Content: body, Heading: heading, Source: source, Page: page})
+	}
+
+	return sections
+}
+
+// HTMLLoader divide la pagina in una Section per ogni heading, come
+// MarkdownLoader.
+type HTMLLoader struct{}
+
+func (HTMLLoader) Load(path string) ([]Section, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("errore lettura file HTML: %v", err)
+	}
+
+	sections := htmlToSections(string(content), filepath.Base(path), 0)
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("file HTML troppo corto o vuoto")
+	}
+	return sections, nil
+}
+
+// wordDocumentXML è lo schema minimo necessario per estrarre il testo
+// dai paragrafi di word/document.xml dentro un .docx (che è uno zip).
+type wordDocumentXML struct {
+	Body struct {
+		Paragraphs []struct {
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"p"`
+	} `xml:"body"`
+}
+
+// DocxLoader estrae il testo di word/document.xml senza dipendere da una
+// libreria esterna per il formato Office Open XML: un .docx è uno zip, e
+// i paragrafi interessano solo un piccolo sottoinsieme dello schema.
+type DocxLoader struct{}
+
+func (DocxLoader) Load(path string) ([]Section, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("errore apertura DOCX: %v", err)
+	}
+	defer reader.Close()
+
+	var docXML *zip.File
+	for _, f := range reader.File {
+		if f.Name == "word/document.xml" {
+			docXML = f
+			break
+		}
+	}
+	if docXML == nil {
+		return nil, fmt.Errorf("word/document.xml non trovato nel DOCX")
+	}
+
+	rc, err := docXML.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc wordDocumentXML
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("errore parsing DOCX: %v", err)
+	}
+
+	var paragraphs []string
+	for _, p := range doc.Body.Paragraphs {
+		var text strings.Builder
+		for _, run := range p.Runs {
+			text.WriteString(run.Text)
+		}
+		if trimmed := strings.TrimSpace(text.String()); trimmed != "" {
+			paragraphs = append(paragraphs, trimmed)
+		}
+	}
+
+	content := cleanText(strings.Join(paragraphs, "\n\n"))
+	if len(content) < 50 {
+		return nil, fmt.Errorf("file DOCX troppo corto o vuoto")
+	}
+
+	return []Section{{Content: content, Source: filepath.Base(path)}}, nil
+}
+
+// epubContainerXML individua il file content.opf (il "manifest") dentro
+// META-INF/container.xml, punto di ingresso standard di ogni EPUB.
+type epubContainerXML struct {
+	Rootfiles struct {
+		Rootfile []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfile"`
+	} `xml:"rootfiles"`
+}
+
+// epubPackageXML è lo schema minimo di content.opf: la spine elenca, in
+// ordine di lettura, gli idref dei capitoli registrati nel manifest.
+type epubPackageXML struct {
+	Manifest struct {
+		Items []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"item"`
+	} `xml:"manifest"`
+	Spine struct {
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+// EpubLoader legge i capitoli XHTML di un EPUB nell'ordine della spine
+// del manifest e li divide in Section per heading con htmlToSections,
+// la stessa logica usata per l'HTML.
+type EpubLoader struct{}
+
+func (EpubLoader) Load(path string) ([]Section, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("errore apertura EPUB: %v", err)
+	}
+	defer reader.Close()
+
+	files := map[string]*zip.File{}
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+
+	containerData, err := readZipFile(files, "META-INF/container.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var container epubContainerXML
+	if err := xml.Unmarshal(containerData, &container); err != nil {
+		return nil, fmt.Errorf("errore parsing container.xml: %v", err)
+	}
+	if len(container.Rootfiles.Rootfile) == 0 {
+		return nil, fmt.Errorf("rootfile non trovato in container.xml")
+	}
+
+	opfPath := container.Rootfiles.Rootfile[0].FullPath
+	opfData, err := readZipFile(files, opfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg epubPackageXML
+	if err := xml.Unmarshal(opfData, &pkg); err != nil {
+		return nil, fmt.Errorf("errore parsing content.opf: %v", err)
+	}
+
+	hrefByID := map[string]string{}
+	for _, item := range pkg.Manifest.Items {
+		hrefByID[item.ID] = item.Href
+	}
+
+	opfDir := filepath.Dir(opfPath)
+	source := filepath.Base(path)
+
+	var sections []Section
+	for i, ref := range pkg.Spine.ItemRefs {
+		href, ok := hrefByID[ref.IDRef]
+		if !ok {
+			continue
+		}
+
+		chapterPath := href
+		if opfDir != "." {
+			chapterPath = opfDir + "/" + href
+		}
+
+		chapterData, err := readZipFile(files, chapterPath)
+		if err != nil {
+			log.Printf("Errore lettura capitolo EPUB %s: %v", chapterPath, err)
+			continue
+		}
+
+		sections = append(sections, htmlToSections(string(chapterData), source, i+1)...)
+	}
+
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("nessun capitolo leggibile nell'EPUB")
+	}
+
+	return sections, nil
+}
+
+func readZipFile(files map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("file non trovato nell'archivio: %s", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
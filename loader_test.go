@@ -0,0 +1,185 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("scrittura file di test: %v", err)
+	}
+	return path
+}
+
+// writeZip costruisce un archivio zip minimo con i file indicati, utile a
+// fabbricare fixture DOCX/EPUB al volo senza dover portare binari nel repo.
+func writeZip(t *testing.T, name string, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("creazione zip di test: %v", err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for entryName, content := range files {
+		entry, err := w.Create(entryName)
+		if err != nil {
+			t.Fatalf("creazione voce zip %s: %v", entryName, err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatalf("scrittura voce zip %s: %v", entryName, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("chiusura zip di test: %v", err)
+	}
+
+	return path
+}
+
+func TestTXTLoaderLoadReturnsSingleUnpaginatedSection(t *testing.T) {
+	path := writeTemp(t, "note.txt", strings.Repeat("una frase di prova ", 10))
+
+	sections, err := TXTLoader{}.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(sections) != 1 {
+		t.Fatalf("sections = %d, attese 1", len(sections))
+	}
+	if sections[0].Page != 0 {
+		t.Fatalf("Page = %d, atteso 0 per un file TXT", sections[0].Page)
+	}
+}
+
+func TestMarkdownLoaderLoadSplitsByHeading(t *testing.T) {
+	content := "# Prima\n" + strings.Repeat("contenuto prima sezione ", 10) +
+		"\n## Seconda\n" + strings.Repeat("contenuto seconda sezione ", 10)
+	path := writeTemp(t, "doc.md", content)
+
+	sections, err := MarkdownLoader{}.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("sections = %d, attese 2 (una per heading)", len(sections))
+	}
+	if sections[0].Heading != "Prima" || sections[1].Heading != "Seconda" {
+		t.Fatalf("heading = %q, %q; attesi \"Prima\", \"Seconda\"", sections[0].Heading, sections[1].Heading)
+	}
+}
+
+func TestHTMLLoaderLoadStripsTagsAndScripts(t *testing.T) {
+	content := "<html><body><script>alert(1)</script><h1>Titolo</h1><p>" +
+		strings.Repeat("testo visibile ", 5) + "</p></body></html>"
+	path := writeTemp(t, "page.html", content)
+
+	sections, err := HTMLLoader{}.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(sections) != 1 {
+		t.Fatalf("sections = %d, attesa 1", len(sections))
+	}
+	if strings.Contains(sections[0].Content, "alert") {
+		t.Fatalf("Content = %q, lo script non doveva comparire nel testo estratto", sections[0].Content)
+	}
+	if sections[0].Heading != "Titolo" {
+		t.Fatalf("Heading = %q, atteso \"Titolo\"", sections[0].Heading)
+	}
+}
+
+func TestDocxLoaderLoadExtractsParagraphText(t *testing.T) {
+	documentXML := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:body>
+    <w:p><w:r><w:t>Primo paragrafo del documento, abbastanza lungo da superare la soglia minima.</w:t></w:r></w:p>
+    <w:p><w:r><w:t>Secondo paragrafo.</w:t></w:r></w:p>
+  </w:body>
+</w:document>`
+	path := writeZip(t, "doc.docx", map[string]string{
+		"word/document.xml": documentXML,
+	})
+
+	sections, err := DocxLoader{}.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(sections) != 1 {
+		t.Fatalf("sections = %d, attesa 1 (DocxLoader produce un'unica Section)", len(sections))
+	}
+	if !strings.Contains(sections[0].Content, "Primo paragrafo") || !strings.Contains(sections[0].Content, "Secondo paragrafo") {
+		t.Fatalf("Content = %q, attesi entrambi i paragrafi", sections[0].Content)
+	}
+}
+
+func TestDocxLoaderLoadMissingDocumentXMLFails(t *testing.T) {
+	path := writeZip(t, "doc.docx", map[string]string{
+		"word/other.xml": "<x/>",
+	})
+
+	if _, err := (DocxLoader{}).Load(path); err == nil {
+		t.Fatalf("Load doveva fallire senza word/document.xml")
+	}
+}
+
+func TestEpubLoaderLoadFollowsManifestAndSpine(t *testing.T) {
+	containerXML := `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>`
+
+	contentOPF := `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <manifest>
+    <item id="chap1" href="chapter1.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="chap1"/>
+  </spine>
+</package>`
+
+	chapter1 := `<html><body><h1>Capitolo Uno</h1><p>` +
+		strings.Repeat("contenuto del capitolo ", 5) + `</p></body></html>`
+
+	path := writeZip(t, "book.epub", map[string]string{
+		"META-INF/container.xml": containerXML,
+		"OEBPS/content.opf":      contentOPF,
+		"OEBPS/chapter1.xhtml":   chapter1,
+	})
+
+	sections, err := EpubLoader{}.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(sections) != 1 {
+		t.Fatalf("sections = %d, attesa 1 (un heading nel capitolo)", len(sections))
+	}
+	if sections[0].Heading != "Capitolo Uno" {
+		t.Fatalf("Heading = %q, atteso \"Capitolo Uno\"", sections[0].Heading)
+	}
+	if sections[0].Page != 1 {
+		t.Fatalf("Page = %d, atteso 1 (indice del capitolo nella spine)", sections[0].Page)
+	}
+}
+
+func TestEpubLoaderLoadMissingContainerFails(t *testing.T) {
+	path := writeZip(t, "book.epub", map[string]string{
+		"OEBPS/content.opf": "<package/>",
+	})
+
+	if _, err := (EpubLoader{}).Load(path); err == nil {
+		t.Fatalf("Load doveva fallire senza META-INF/container.xml")
+	}
+}
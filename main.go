@@ -2,20 +2,15 @@ package main
 
 import (
 	"bufio"
-	"bytes"
-	"crypto/md5"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"math"
-	"net/http"
 	"os"
-	"regexp"
-	"sort"
 	"strings"
 	"time"
-
-	"github.com/ledongthuc/pdf"
 )
 
 // Strutture dati
@@ -23,6 +18,9 @@ type Document struct {
 	ID      string    `json:"id"`
 	Content string    `json:"content"`
 	Page    int       `json:"page"`
+	Source  string    `json:"source,omitempty"`
+	Section string    `json:"section,omitempty"`
+	Heading string    `json:"heading,omitempty"`
 	Vector  []float64 `json:"vector"`
 }
 
@@ -61,70 +59,107 @@ type EmbeddingResponse struct {
 }
 
 type RAGChatbot struct {
-	vectorStore   *VectorStore
-	ollamaBaseURL string
-	embedModel    string
-	chatModel     string
-	dbPath        string
+	vectorStore  *VectorStore
+	chatBackend  ChatBackend
+	embedBackend EmbeddingBackend
+	embedModel   string
+	dbPath       string
+	index        Index
+	indexType    string
+	indexPath    string
+	bm25         *BM25Index
+	genOpts      GenOpts
+
+	retrievalMode string // "vector", "bm25" o "hybrid"
+	vectorWeight  float64
+	bm25Weight    float64
+	rrfK          int
+
+	lastRetrievers map[string][]string // per l'ultima query: ID documento -> retriever che l'ha proposto
 }
 
-// Inizializza il chatbot
-func NewRAGChatbot() *RAGChatbot {
+// Inizializza il chatbot leggendo da variabili d'ambiente quale backend
+// di chat e di embedding usare (default: Ollama locale per entrambi).
+func NewRAGChatbot() (*RAGChatbot, error) {
+	embedCfg := LoadEmbedBackendConfig()
+	embedBackend, err := NewEmbeddingBackend(embedCfg)
+	if err != nil {
+		return nil, fmt.Errorf("configurazione backend embedding non valida: %v", err)
+	}
+
+	chatCfg := LoadChatBackendConfig()
+	chatBackend, err := NewChatBackend(chatCfg)
+	if err != nil {
+		return nil, fmt.Errorf("configurazione backend chat non valida: %v", err)
+	}
+
 	return &RAGChatbot{
 		vectorStore:   &VectorStore{Documents: []Document{}},
-		ollamaBaseURL: "http://localhost:11434",
-		embedModel:    "nomic-embed-text", // Modello di embedding
-		chatModel:     "",                 // Modello di chat
+		chatBackend:   chatBackend,
+		embedBackend:  embedBackend,
+		embedModel:    embedCfg.Model,
 		dbPath:        "vectorstore.json",
-	}
+		indexType:     envOrDefault("RETRIEVAL_INDEX", "flat"), // "flat" o "hnsw"
+		indexPath:     "vectorstore.hnsw.json",
+		retrievalMode: "vector",
+		vectorWeight:  envFloatOrDefault("RETRIEVAL_VECTOR_WEIGHT", 1.0),
+		bm25Weight:    envFloatOrDefault("RETRIEVAL_BM25_WEIGHT", 1.0),
+		rrfK:          60,
+		genOpts: GenOpts{
+			Temperature: chatCfg.Temperature,
+			TopK:        chatCfg.TopK,
+			TopP:        chatCfg.TopP,
+		},
+	}, nil
 }
 
-// Estrae testo dal PDF
-func (r *RAGChatbot) ExtractTextFromPDF(filename string) ([]string, error) {
-	file, reader, err := pdf.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("errore apertura PDF: %v", err)
+// ensureIndex costruisce (o ricarica) l'indice vettoriale e l'indice BM25
+// se non sono già pronti. Per "hnsw" il grafo serializzato viene
+// ricaricato da disco e rigenerato solo se manca o se il modello di
+// embedding è cambiato.
+func (r *RAGChatbot) ensureIndex() {
+	if r.bm25 == nil {
+		bm25 := NewBM25Index()
+		for _, doc := range r.vectorStore.Documents {
+			bm25.Insert(doc)
+		}
+		r.bm25 = bm25
 	}
-	defer file.Close()
 
-	var pages []string
-	totalPages := reader.NumPage()
-
-	fmt.Printf("Elaborazione PDF: %d pagine trovate\n", totalPages)
+	if r.index != nil {
+		return
+	}
 
-	for pageNum := 1; pageNum <= totalPages; pageNum++ {
-		page := reader.Page(pageNum)
-		if page.V.IsNull() {
-			continue
+	if r.indexType == "hnsw" {
+		if idx, err := LoadHNSWIndex(r.indexPath); err == nil && idx.ModelName == r.vectorStore.ModelName {
+			r.index = idx
+			return
 		}
 
-		content, err := page.GetPlainText(nil)
-		if err != nil {
-			log.Printf("Errore estrazione pagina %d: %v", pageNum, err)
-			continue
+		idx := NewHNSWIndex()
+		idx.ModelName = r.vectorStore.ModelName
+		for _, doc := range r.vectorStore.Documents {
+			idx.Insert(doc)
 		}
-
-		// Pulisci il testo
-		cleanContent := r.cleanText(content)
-		if len(cleanContent) > 50 { // Solo se ha contenuto significativo
-			pages = append(pages, cleanContent)
+		if err := idx.Save(r.indexPath); err != nil {
+			log.Printf("Attenzione: impossibile salvare l'indice HNSW: %v", err)
 		}
+		r.index = idx
+		return
 	}
 
-	return pages, nil
+	flat := NewFlatIndex()
+	for _, doc := range r.vectorStore.Documents {
+		flat.Insert(doc)
+	}
+	r.index = flat
 }
 
-// Pulisce il testo estratto
-func (r *RAGChatbot) cleanText(text string) string {
-	// Rimuovi caratteri di controllo e normalizza spazi
-	reg := regexp.MustCompile(`\s+`)
-	text = reg.ReplaceAllString(text, " ")
-
-	// Rimuovi caratteri non stampabili
-	reg = regexp.MustCompile(`[^\p{L}\p{N}\p{P}\p{Z}]+`)
-	text = reg.ReplaceAllString(text, " ")
-
-	return strings.TrimSpace(text)
+// RetrieversFor indica quali retriever hanno contribuito al documento
+// docID nell'ultima chiamata a SearchSimilar, utile per stamparlo nella
+// sezione "Fonti utilizzate".
+func (r *RAGChatbot) RetrieversFor(docID string) []string {
+	return r.lastRetrievers[docID]
 }
 
 // Suddivide il testo in chunks
@@ -151,34 +186,9 @@ func (r *RAGChatbot) ChunkText(text string, chunkSize int, overlap int) []string
 	return chunks
 }
 
-// Genera embedding tramite Ollama
+// Genera embedding tramite il backend di embedding configurato
 func (r *RAGChatbot) GetEmbedding(text string) ([]float64, error) {
-	reqBody := EmbeddingRequest{
-		Model: r.embedModel,
-		Input: text,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.Post(r.ollamaBaseURL+"/api/embed", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("errore chiamata Ollama embed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var embedResp EmbeddingResponse
-	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
-		return nil, err
-	}
-
-	if len(embedResp.Embeddings) == 0 {
-		return nil, fmt.Errorf("nessun embedding ricevuto")
-	}
-
-	return embedResp.Embeddings[0], nil
+	return r.embedBackend.Embed(context.Background(), text)
 }
 
 // Calcola similarità coseno
@@ -204,63 +214,10 @@ func cosineSimilarity(a, b []float64) float64 {
 	return dotProduct / (normA * normB)
 }
 
-// Elabora PDF e crea vector store
+// Elabora PDF e crea vector store. Resta un alias di ProcessFile per
+// compatibilità con il codice esistente che elaborava solo PDF.
 func (r *RAGChatbot) ProcessPDF(filename string) error {
-	fmt.Println("📄 Estrazione testo dal PDF...")
-	pages, err := r.ExtractTextFromPDF(filename)
-	if err != nil {
-		return err
-	}
-
-	fmt.Printf("✅ Estratte %d pagine\n", len(pages))
-
-	r.vectorStore.Documents = []Document{}
-
-	fmt.Println("🔤 Creazione chunks e embedding...")
-	totalChunks := 0
-
-	for pageNum, pageText := range pages {
-		// Crea chunks per ogni pagina
-		chunks := r.ChunkText(pageText, 300, 50) // 300 parole per chunk, overlap 50
-
-		for chunkIdx, chunk := range chunks {
-			if len(strings.TrimSpace(chunk)) < 20 {
-				continue
-			}
-
-			// Genera ID unico
-			hasher := md5.New()
-			hasher.Write([]byte(chunk))
-			docID := fmt.Sprintf("page_%d_chunk_%d_%x", pageNum+1, chunkIdx, hasher.Sum(nil)[:4])
-
-			fmt.Printf("🔄 Processando chunk %d/%d (pagina %d)\r", totalChunks+1, len(chunks), pageNum+1)
-
-			// Genera embedding
-			vector, err := r.GetEmbedding(chunk)
-			if err != nil {
-				log.Printf("Errore embedding per chunk %s: %v", docID, err)
-				continue
-			}
-
-			doc := Document{
-				ID:      docID,
-				Content: chunk,
-				Page:    pageNum + 1,
-				Vector:  vector,
-			}
-
-			r.vectorStore.Documents = append(r.vectorStore.Documents, doc)
-			totalChunks++
-
-			// Pausa per non sovraccaricare Ollama
-			time.Sleep(100 * time.Millisecond)
-		}
-	}
-
-	r.vectorStore.ModelName = r.embedModel
-	fmt.Printf("\n✅ Creati %d chunks con embedding\n", totalChunks)
-
-	return r.SaveVectorStore()
+	return r.ProcessFile(filename)
 }
 
 // Salva vector store su file
@@ -284,57 +241,102 @@ func (r *RAGChatbot) LoadVectorStore() error {
 		return err
 	}
 
-	return json.Unmarshal(data, r.vectorStore)
+	if err := json.Unmarshal(data, r.vectorStore); err != nil {
+		return err
+	}
+
+	r.index = nil // il set di documenti è cambiato, indice e BM25 vanno ricostruiti
+	r.bm25 = nil
+	return nil
 }
 
-// Ricerca documenti simili
+// Ricerca documenti simili, instradando su vettoriale, BM25 o entrambi
+// fusi con Reciprocal Rank Fusion a seconda di r.retrievalMode.
 func (r *RAGChatbot) SearchSimilar(query string, topK int) ([]Document, error) {
-	queryVector, err := r.GetEmbedding(query)
-	if err != nil {
-		return nil, err
-	}
+	r.ensureIndex()
 
-	type ScoredDocument struct {
-		Document Document
-		Score    float64
-	}
+	switch r.retrievalMode {
+	case "bm25":
+		docs := capDocuments(r.bm25.Rank(query), topK)
+		r.lastRetrievers = provenanceFor(docs, "bm25")
+		return docs, nil
 
-	var scoredDocs []ScoredDocument
+	case "hybrid":
+		queryVector, err := r.GetEmbedding(query)
+		if err != nil {
+			return nil, err
+		}
 
-	for _, doc := range r.vectorStore.Documents {
-		similarity := cosineSimilarity(queryVector, doc.Vector)
-		scoredDocs = append(scoredDocs, ScoredDocument{
-			Document: doc,
-			Score:    similarity,
-		})
-	}
+		pool := topK * 4
+		if pool < 20 {
+			pool = 20
+		}
+
+		vectorRanked := r.index.Search(queryVector, pool)
+		bm25Ranked := r.bm25.Rank(query)
 
-	// Ordina per similarità decrescente
-	sort.Slice(scoredDocs, func(i, j int) bool {
-		return scoredDocs[i].Score > scoredDocs[j].Score
-	})
+		docs, retrievers := fuseRankings(vectorRanked, bm25Ranked, topK, r.rrfK, r.vectorWeight, r.bm25Weight)
+		r.lastRetrievers = retrievers
+		return docs, nil
 
-	// Prendi i top K
-	if topK > len(scoredDocs) {
-		topK = len(scoredDocs)
+	default: // "vector"
+		queryVector, err := r.GetEmbedding(query)
+		if err != nil {
+			return nil, err
+		}
+
+		docs := r.index.Search(queryVector, topK)
+		r.lastRetrievers = provenanceFor(docs, "vector")
+		return docs, nil
 	}
+}
 
-	var results []Document
-	for i := 0; i < topK; i++ {
-		results = append(results, scoredDocs[i].Document)
+// capDocuments tronca una lista già ordinata ai primi topK elementi.
+func capDocuments(docs []Document, topK int) []Document {
+	if topK > len(docs) {
+		topK = len(docs)
 	}
+	return docs[:topK]
+}
+
+// provenanceFor costruisce la mappa ID documento -> retriever per un
+// risultato proveniente da un solo retriever.
+func provenanceFor(docs []Document, retriever string) map[string][]string {
+	m := make(map[string][]string, len(docs))
+	for _, doc := range docs {
+		m[doc.ID] = []string{retriever}
+	}
+	return m
+}
 
-	return results, nil
+// citationLabel descrive la provenienza di un Document per le citazioni:
+// "file X, sezione Y" quando il Loader ha un titolo di sezione, altrimenti
+// "file X, pagina N" se il Document ha una pagina, "file X, Sezione N"
+// (il campo Section) se non ce l'ha o, in assenza di un Source (vecchi
+// vector store), la semplice numerazione di pagina usata storicamente per
+// i PDF.
+func citationLabel(doc Document) string {
+	switch {
+	case doc.Source != "" && doc.Heading != "":
+		return fmt.Sprintf("file %s, sezione %q", doc.Source, doc.Heading)
+	case doc.Source != "" && doc.Page > 0:
+		return fmt.Sprintf("file %s, pagina %d", doc.Source, doc.Page)
+	case doc.Source != "":
+		return fmt.Sprintf("file %s, %s", doc.Source, doc.Section)
+	default:
+		return fmt.Sprintf("Pagina %d", doc.Page)
+	}
 }
 
-// Genera risposta tramite Ollama
-func (r *RAGChatbot) GenerateResponse(question string, context []Document) (string, error) {
-	// Costruisci il contesto
+// buildChatPrompt costruisce il prompt italiano con il contesto recuperato
+// e i parametri di campionamento, condivisi tra generazione sincrona e
+// in streaming.
+func (r *RAGChatbot) buildChatPrompt(question string, docs []Document) (string, GenOpts) {
 	var contextText strings.Builder
 	contextText.WriteString("Contesto dal documento:\n\n")
 
-	for i, doc := range context {
-		contextText.WriteString(fmt.Sprintf("Sezione %d (Pagina %d):\n%s\n\n", i+1, doc.Page, doc.Content))
+	for i, doc := range docs {
+		contextText.WriteString(fmt.Sprintf("Sezione %d (%s):\n%s\n\n", i+1, citationLabel(doc), doc.Content))
 	}
 
 	// Prompt ottimizzato per l'italiano
@@ -349,43 +351,71 @@ Istruzioni:
 - Usa ESCLUSIVAMENTE le informazioni del contesto fornito
 - Se la risposta non è presente nel documento, dillo chiaramente
 - Sii preciso e dettagliato
-- Cita la pagina quando possibile
+- Cita la fonte (file, sezione o pagina) quando possibile
 
 Risposta:`, contextText.String(), question)
 
-	// default
-	/*reqBody := OllamaRequest{
-		Model:  r.chatModel,
-		Prompt: prompt,
-		Stream: false,
-	}*/
+	return prompt, r.genOpts
+}
 
-	reqBody := OllamaRequest3T{
-		Model:       r.chatModel,
-		Prompt:      prompt,
-		Stream:      false,
-		Temperature: 0.2, // Bassa temperatura per risposte più precise e consistenti
-		TopK:        40,  // Limita le opzioni di token
-		TopP:        0.9, // Nucleus sampling
-	}
+// Genera risposta tramite il backend di chat configurato
+func (r *RAGChatbot) GenerateResponse(question string, docs []Document) (string, error) {
+	prompt, opts := r.buildChatPrompt(question, docs)
+	return r.chatBackend.Generate(context.Background(), prompt, opts)
+}
 
-	jsonData, err := json.Marshal(reqBody)
+// GenerateResponseStream funziona come GenerateResponse ma emette la
+// risposta token per token man mano che il backend la produce (per
+// Ollama, leggendo il suo stream NDJSON), cosa che il REPL a menu non
+// sfrutta ma su cui si appoggia la TUI.
+func (r *RAGChatbot) GenerateResponseStream(question string, docs []Document) (<-chan string, error) {
+	prompt, opts := r.buildChatPrompt(question, docs)
+
+	tokens, err := r.chatBackend.GenerateStream(context.Background(), prompt, opts)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for tok := range tokens {
+			if tok.Content != "" {
+				out <- tok.Content
+			}
+			if tok.Done {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// ChatStream funziona come Chat ma restituisce la risposta token per
+// token: il recupero dei documenti resta sincrono (serve comunque prima
+// di generare il prompt), solo la generazione vera e propria passa per
+// GenerateResponseStream, così che la TUI possa mostrarla man mano che
+// arriva invece di attendere la risposta completa.
+func (r *RAGChatbot) ChatStream(question string) ([]Document, <-chan string, error) {
+	if len(r.vectorStore.Documents) == 0 {
+		out := make(chan string, 1)
+		out <- "Per favore, carica prima un documento PDF."
+		close(out)
+		return nil, out, nil
 	}
 
-	resp, err := http.Post(r.ollamaBaseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	similarDocs, err := r.SearchSimilar(question, 4)
 	if err != nil {
-		return "", fmt.Errorf("errore chiamata Ollama: %v", err)
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
 
-	var response OllamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", err
+	tokens, err := r.GenerateResponseStream(question, similarDocs)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return response.Response, nil
+	return similarDocs, tokens, nil
 }
 
 // Chat con il documento
@@ -409,94 +439,55 @@ func (r *RAGChatbot) Chat(question string) (string, []Document, error) {
 	return answer, similarDocs, nil
 }
 
-// Verifica se Ollama è disponibile
+// Verifica se il backend di chat configurato è disponibile
 func (r *RAGChatbot) CheckOllamaAvailable() error {
-	resp, err := http.Get(r.ollamaBaseURL + "/api/tags")
-	if err != nil {
-		return fmt.Errorf("Ollama non disponibile su %s: %v", r.ollamaBaseURL, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("Ollama risponde con status: %d", resp.StatusCode)
-	}
-
-	return nil
+	return r.chatBackend.Ping(context.Background())
 }
 
-// Elabora file TXT e crea vector store
-func (r *RAGChatbot) ProcessTXT(filename string) error {
-	fmt.Println("� Lettura file TXT...")
-
-	// Leggi tutto il contenuto del file
-	content, err := os.ReadFile(filename)
+// ChatInConversation esegue Chat e registra domanda e risposta come nodi
+// figli dell'head corrente della conversazione, avanzando l'head sul
+// nuovo nodo assistant.
+func (r *RAGChatbot) ChatInConversation(conv *Conversation, question string) (string, []Document, error) {
+	answer, sources, err := r.Chat(question)
 	if err != nil {
-		return fmt.Errorf("errore lettura file TXT: %v", err)
+		return "", nil, err
 	}
 
-	text := string(content)
+	userNode := conv.AddMessage(conv.HeadID, Message{Role: "user", Content: question})
 
-	// Pulisci il testo
-	cleanedText := r.cleanText(text)
-
-	if len(strings.TrimSpace(cleanedText)) < 50 {
-		return fmt.Errorf("file TXT troppo corto o vuoto")
+	sourceIDs := make([]string, 0, len(sources))
+	for _, doc := range sources {
+		sourceIDs = append(sourceIDs, doc.ID)
 	}
 
-	fmt.Printf("✅ File letto: %d caratteri\n", len(cleanedText))
-
-	// Reset del vector store
-	r.vectorStore.Documents = []Document{}
-
-	fmt.Println("� Creazione chunks e embedding...")
-
-	// Crea chunks dal testo completo
-	chunks := r.ChunkText(cleanedText, 300, 50) // 300 parole per chunk, overlap 50
-
-	fmt.Printf("� Creati %d chunks\n", len(chunks))
-
-	for chunkIdx, chunk := range chunks {
-		if len(strings.TrimSpace(chunk)) < 20 {
-			continue
-		}
-
-		// Genera ID unico per il chunk
-		hasher := md5.New()
-		hasher.Write([]byte(chunk))
-		docID := fmt.Sprintf("txt_chunk_%d_%x", chunkIdx, hasher.Sum(nil)[:4])
-		// TODO nel caso di problema di encoding per lettere accentate
-		//docID := fmt.Sprintf("txt_%s_chunk_%d_%x", "ISO-8859-1", chunkIdx, hasher.Sum(nil)[:4])
-
-		fmt.Printf("� Processando chunk %d/%d\r", chunkIdx+1, len(chunks))
+	conv.AddMessage(userNode.ID, Message{Role: "assistant", Content: answer, SourceIDs: sourceIDs})
 
-		// Genera embedding
-		vector, err := r.GetEmbedding(chunk)
-		if err != nil {
-			log.Printf("Errore embedding per chunk %s: %v", docID, err)
-			continue
-		}
+	return answer, sources, nil
+}
 
-		doc := Document{
-			ID:      docID,
-			Content: chunk,
-			Page:    1, // Per file TXT usiamo sempre pagina 1
-			Vector:  vector,
-		}
+// Elabora file TXT e crea vector store. Resta un alias di ProcessFile per
+// compatibilità con il codice esistente che elaborava solo TXT.
+func (r *RAGChatbot) ProcessTXT(filename string) error {
+	return r.ProcessFile(filename)
+}
 
-		r.vectorStore.Documents = append(r.vectorStore.Documents, doc)
+func main() {
+	classic := flag.Bool("classic", false, "usa il menu testuale classico invece della TUI interattiva")
+	maxSteps := flag.Int("max-steps", 5, "numero massimo di passi dell'agente tool-calling per domanda")
+	retrieval := flag.String("retrieval", "vector", "strategia di recupero: vector, bm25 o hybrid")
+	flag.Parse()
 
-		// Pausa per non sovraccaricare Ollama
-		time.Sleep(100 * time.Millisecond)
+	chatbot, err := NewRAGChatbot()
+	if err != nil {
+		log.Fatal("❌ ", err)
 	}
 
-	r.vectorStore.ModelName = r.embedModel
-	fmt.Printf("\n✅ Creati %d chunks con embedding\n", len(r.vectorStore.Documents))
-
-	return r.SaveVectorStore()
-}
-
-func main() {
-	chatbot := NewRAGChatbot()
+	switch *retrieval {
+	case "vector", "bm25", "hybrid":
+		chatbot.retrievalMode = *retrieval
+	default:
+		log.Fatalf("❌ --retrieval non valido: %s (atteso vector, bm25 o hybrid)", *retrieval)
+	}
 
 	fmt.Println("🤖 Chatbot RAG Offline per PDF Italiani")
 	fmt.Println("=====================================")
@@ -516,45 +507,67 @@ func main() {
 		fmt.Printf("✅ Database caricato: %d documenti\n", len(chatbot.vectorStore.Documents))
 	}
 
+	if !*classic {
+		if err := RunTUI(chatbot); err != nil {
+			log.Fatal("❌ ", err)
+		}
+		return
+	}
+
+	runClassicMenu(chatbot, *maxSteps)
+}
+
+// runClassicMenu esegue il vecchio REPL a menu numerato, disponibile
+// dietro il flag --classic per chi preferisce l'interfaccia testuale
+// semplice alla TUI interattiva.
+func runClassicMenu(chatbot *RAGChatbot, maxSteps int) {
 	reader := bufio.NewReader(os.Stdin)
 
+	convStore := NewConversationStore("conversations.json")
+	if err := convStore.Load(); err != nil {
+		fmt.Println("⚠️  Nessun archivio conversazioni trovato")
+	}
+	var currentConvID string
+
 	for {
 		fmt.Println("\n📋 Opzioni disponibili:")
-		fmt.Println("1. Elabora nuovo PDF")
+		fmt.Println("1. Elabora nuovo file (pdf, txt, md, html, docx, epub)")
 		fmt.Println("2. Fai una domanda")
 		fmt.Println("3. Mostra statistiche database")
 		fmt.Println("4. Esci")
-		fmt.Print("\nScegli un'opzione (1-4): ")
+		fmt.Println("5. Gestisci conversazioni")
+		fmt.Println("6. Fai una domanda (agente multi-hop)")
+		fmt.Println("7. Elabora una cartella intera")
+		fmt.Print("\nScegli un'opzione (1-7): ")
 
 		choice, _ := reader.ReadString('\n')
 		choice = strings.TrimSpace(choice)
 
 		switch choice {
 		case "1":
-			fmt.Print("\n📄 Inserisci il percorso del file PDF: ")
-			pdfPath, _ := reader.ReadString('\n')
-			pdfPath = strings.TrimSpace(pdfPath)
+			fmt.Print("\n📄 Inserisci il percorso del file: ")
+			filePath, _ := reader.ReadString('\n')
+			filePath = strings.TrimSpace(filePath)
 
-			if _, err := os.Stat(pdfPath); os.IsNotExist(err) {
+			if _, err := os.Stat(filePath); os.IsNotExist(err) {
 				fmt.Println("❌ File non trovato")
 				continue
 			}
 
-			fmt.Println("\n🚀 Inizio elaborazione PDF...")
+			fmt.Println("\n🚀 Inizio elaborazione file...")
 			start := time.Now()
 
-			// TODO estrazione da txt: chatbot.ProcessTXT(pdfPath)
-			if err := chatbot.ProcessPDF(pdfPath); err != nil {
+			if err := chatbot.ProcessFile(filePath); err != nil {
 				fmt.Printf("❌ Errore: %v\n", err)
 			} else {
 				duration := time.Since(start)
-				fmt.Printf("✅ PDF elaborato con successo in %v\n", duration)
+				fmt.Printf("✅ File elaborato con successo in %v\n", duration)
 				fmt.Printf("📊 Documenti nel database: %d\n", len(chatbot.vectorStore.Documents))
 			}
 
 		case "2":
 			if len(chatbot.vectorStore.Documents) == 0 {
-				fmt.Println("⚠️  Carica prima un PDF!")
+				fmt.Println("⚠️  Carica prima un documento!")
 				continue
 			}
 
@@ -583,7 +596,8 @@ func main() {
 			if len(sources) > 0 {
 				fmt.Println("\n📚 Fonti utilizzate:")
 				for i, source := range sources {
-					fmt.Printf("\n🔹 Fonte %d (Pagina %d):\n", i+1, source.Page)
+					retrievers := strings.Join(chatbot.RetrieversFor(source.ID), "+")
+					fmt.Printf("\n🔹 Fonte %d (%s, via %s):\n", i+1, citationLabel(source), retrievers)
 					preview := source.Content
 					/*if len(preview) > 200 {
 						preview = preview[:200] + "..."
@@ -616,8 +630,201 @@ func main() {
 			fmt.Println("\n👋 Arrivederci!")
 			return
 
+		case "5":
+			manageConversations(chatbot, convStore, reader, &currentConvID)
+
+		case "6":
+			if len(chatbot.vectorStore.Documents) == 0 {
+				fmt.Println("⚠️  Carica prima un documento!")
+				continue
+			}
+
+			fmt.Print("\n❓ Inserisci la tua domanda: ")
+			question, _ := reader.ReadString('\n')
+			question = strings.TrimSpace(question)
+			if question == "" {
+				continue
+			}
+
+			fmt.Println("\n🤔 Ragionamento dell'agente in corso...")
+			answer, trace, err := chatbot.ChatWithAgent(question, maxSteps)
+			if err != nil {
+				fmt.Printf("❌ Errore: %v\n", err)
+				continue
+			}
+
+			fmt.Println("\n🔧 Traccia delle chiamate a strumenti:")
+			for i, step := range trace {
+				if step.Tool != nil {
+					fmt.Printf("  Passo %d: %s(%v) → %s\n", i+1, step.Tool.Name, step.Tool.Args, previewContent(step.ToolResult, 160))
+				}
+			}
+
+			fmt.Printf("\n💬 Risposta:\n%s\n", answer)
+
+		case "7":
+			fmt.Print("\n📁 Inserisci il percorso della cartella: ")
+			dirPath, _ := reader.ReadString('\n')
+			dirPath = strings.TrimSpace(dirPath)
+
+			if info, err := os.Stat(dirPath); err != nil || !info.IsDir() {
+				fmt.Println("❌ Cartella non trovata")
+				continue
+			}
+
+			fmt.Print("📐 Pattern glob sui nomi file (invio per tutti i formati supportati): ")
+			glob, _ := reader.ReadString('\n')
+			glob = strings.TrimSpace(glob)
+
+			fmt.Println("\n🚀 Inizio elaborazione cartella...")
+			start := time.Now()
+
+			if err := chatbot.ProcessDirectory(dirPath, glob); err != nil {
+				fmt.Printf("❌ Errore: %v\n", err)
+			} else {
+				duration := time.Since(start)
+				fmt.Printf("✅ Cartella elaborata con successo in %v\n", duration)
+				fmt.Printf("📊 Documenti nel database: %d\n", len(chatbot.vectorStore.Documents))
+			}
+
 		default:
 			fmt.Println("❌ Opzione non valida")
 		}
 	}
 }
+
+// manageConversations apre un sotto-prompt a comandi per gestire le
+// conversazioni persistenti: new, reply, view, edit, checkout, rm, branches.
+// currentConvID ricorda la conversazione attiva tra una chiamata e l'altra.
+func manageConversations(chatbot *RAGChatbot, store *ConversationStore, reader *bufio.Reader, currentConvID *string) {
+	fmt.Println("\n💬 Gestione conversazioni (comandi: new, reply, view, edit, checkout, rm, branches, exit)")
+
+	for {
+		if *currentConvID != "" {
+			fmt.Printf("[%s] > ", *currentConvID)
+		} else {
+			fmt.Print("> ")
+		}
+
+		line, _ := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		cmd := parts[0]
+		var arg string
+		if len(parts) > 1 {
+			arg = strings.TrimSpace(parts[1])
+		}
+
+		switch cmd {
+		case "new":
+			conv := store.NewConversation(arg)
+			*currentConvID = conv.ID
+			fmt.Printf("✅ Creata conversazione %s (%q)\n", conv.ID, conv.Title)
+
+		case "reply":
+			conv, ok := store.Conversations[*currentConvID]
+			if !ok {
+				fmt.Println("❌ Nessuna conversazione selezionata, usa \"new\" o \"view <id>\"")
+				continue
+			}
+			if arg == "" {
+				fmt.Println("❌ Uso: reply <domanda>")
+				continue
+			}
+
+			answer, _, err := chatbot.ChatInConversation(conv, arg)
+			if err != nil {
+				fmt.Printf("❌ Errore: %v\n", err)
+				continue
+			}
+			fmt.Printf("💬 %s\n", answer)
+
+		case "view":
+			convID := arg
+			if convID == "" {
+				convID = *currentConvID
+			}
+			conv, ok := store.Conversations[convID]
+			if !ok {
+				fmt.Println("❌ Conversazione non trovata")
+				continue
+			}
+			*currentConvID = conv.ID
+
+			for _, node := range conv.PathToHead() {
+				fmt.Printf("[%s] %s: %s\n", node.ID, node.Message.Role, previewContent(node.Message.Content, 200))
+			}
+
+		case "edit":
+			editParts := strings.SplitN(arg, " ", 2)
+			if len(editParts) < 2 {
+				fmt.Println("❌ Uso: edit <id messaggio> <nuovo contenuto>")
+				continue
+			}
+			conv, ok := store.Conversations[*currentConvID]
+			if !ok {
+				fmt.Println("❌ Nessuna conversazione selezionata")
+				continue
+			}
+
+			sibling, err := conv.EditMessage(editParts[0], editParts[1])
+			if err != nil {
+				fmt.Printf("❌ Errore: %v\n", err)
+				continue
+			}
+			fmt.Printf("✅ Creato branch %s, ora head della conversazione\n", sibling.ID)
+
+		case "checkout":
+			conv, ok := store.Conversations[*currentConvID]
+			if !ok {
+				fmt.Println("❌ Nessuna conversazione selezionata")
+				continue
+			}
+			if arg == "" {
+				fmt.Println("❌ Uso: checkout <id messaggio>")
+				continue
+			}
+
+			if err := conv.Checkout(arg); err != nil {
+				fmt.Printf("❌ Errore: %v\n", err)
+				continue
+			}
+			fmt.Printf("✅ Branch attivo spostato su %s\n", arg)
+
+		case "branches":
+			conv, ok := store.Conversations[*currentConvID]
+			if !ok {
+				fmt.Println("❌ Nessuna conversazione selezionata")
+				continue
+			}
+			for _, childID := range conv.Branches(arg) {
+				child := conv.Nodes[childID]
+				fmt.Printf("[%s] %s: %s\n", child.ID, child.Message.Role, previewContent(child.Message.Content, 120))
+			}
+
+		case "rm":
+			if _, ok := store.Conversations[arg]; !ok {
+				fmt.Println("❌ Conversazione non trovata")
+				continue
+			}
+			delete(store.Conversations, arg)
+			if *currentConvID == arg {
+				*currentConvID = ""
+			}
+			fmt.Printf("🗑️  Conversazione %s rimossa\n", arg)
+
+		case "exit", "back":
+			if err := store.Save(); err != nil {
+				fmt.Printf("❌ Errore salvataggio conversazioni: %v\n", err)
+			}
+			return
+
+		default:
+			fmt.Println("❌ Comando sconosciuto (new, reply, view, edit, checkout, rm, branches, exit)")
+		}
+	}
+}
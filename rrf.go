@@ -0,0 +1,42 @@
+package main
+
+import "sort"
+
+// fuseRankings combina le liste ordinate di due retriever con Reciprocal
+// Rank Fusion: score(d) = peso * 1/(k + rank(d)), sommato sui retriever in
+// cui il documento compare (rank a partire da 1). Restituisce i topK
+// documenti fusi e, per ciascun ID, quali retriever lo hanno proposto.
+func fuseRankings(vectorRanked, bm25Ranked []Document, topK, k int, vectorWeight, bm25Weight float64) ([]Document, map[string][]string) {
+	scores := map[string]float64{}
+	docByID := map[string]Document{}
+	retrievers := map[string][]string{}
+
+	for rank, doc := range vectorRanked {
+		scores[doc.ID] += vectorWeight / float64(k+rank+1)
+		docByID[doc.ID] = doc
+		retrievers[doc.ID] = append(retrievers[doc.ID], "vector")
+	}
+
+	for rank, doc := range bm25Ranked {
+		scores[doc.ID] += bm25Weight / float64(k+rank+1)
+		docByID[doc.ID] = doc
+		retrievers[doc.ID] = append(retrievers[doc.ID], "bm25")
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return scores[ids[i]] > scores[ids[j]] })
+
+	if topK > len(ids) {
+		topK = len(ids)
+	}
+
+	results := make([]Document, topK)
+	for i := 0; i < topK; i++ {
+		results[i] = docByID[ids[i]]
+	}
+
+	return results, retrievers
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestFuseRankingsCombinesBothRetrievers(t *testing.T) {
+	vectorRanked := []Document{{ID: "a"}, {ID: "b"}}
+	bm25Ranked := []Document{{ID: "b"}, {ID: "c"}}
+
+	results, retrievers := fuseRankings(vectorRanked, bm25Ranked, 3, 60, 1.0, 1.0)
+
+	if len(results) != 3 {
+		t.Fatalf("fuseRankings() = %d risultati, attesi 3", len(results))
+	}
+	if results[0].ID != "b" {
+		t.Fatalf("primo risultato = %s, atteso \"b\" perché compare in entrambe le liste", results[0].ID)
+	}
+	if len(retrievers["b"]) != 2 {
+		t.Fatalf("retrievers[\"b\"] = %v, atteso un contributo da entrambi i retriever", retrievers["b"])
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+)
+
+// pane identifica quale riquadro della TUI ha il focus. I tasti vi-like
+// (h/l per cambiare pane, i per entrare in modalità inserimento, esc per
+// uscirne) si applicano in base al pane attivo.
+type pane int
+
+const (
+	paneConversation pane = iota
+	paneSources
+	panePrompt
+)
+
+// tuiModel è il modello bubbletea dell'interfaccia interattiva: tre
+// pannelli (conversazione, fonti recuperate, editor del prompt) più lo
+// stato di input e di focus.
+type tuiModel struct {
+	chatbot  *RAGChatbot
+	renderer *glamour.TermRenderer
+
+	history []string
+	sources []Document
+
+	focus   pane
+	editing bool // true quando il pane prompt è in modalità inserimento (vi-like)
+	input   string
+	status  string
+
+	streaming      bool // true mentre arrivano token della risposta in corso
+	streamQuestion string
+	streamAnswer   string
+}
+
+// streamStartedMsg segnala che il recupero dei documenti è completato e la
+// generazione in streaming è partita: da qui in poi arrivano streamTokenMsg.
+type streamStartedMsg struct {
+	question string
+	sources  []Document
+	tokens   <-chan string
+}
+
+// streamTokenMsg porta il prossimo token del canale aperto da ask(), o
+// ok=false quando il canale si è chiuso e la risposta è completa.
+type streamTokenMsg struct {
+	tokens  <-chan string
+	content string
+	ok      bool
+}
+
+type errMsg struct{ err error }
+
+type editorContentMsg string
+
+// RunTUI avvia l'interfaccia interattiva sul chatbot già inizializzato.
+func RunTUI(chatbot *RAGChatbot) error {
+	renderer, err := glamour.NewTermRenderer(glamour.WithAutoStyle())
+	if err != nil {
+		return fmt.Errorf("errore inizializzazione renderer markdown: %v", err)
+	}
+
+	model := tuiModel{
+		chatbot:  chatbot,
+		renderer: renderer,
+		focus:    panePrompt,
+		editing:  true,
+	}
+
+	_, err = tea.NewProgram(model, tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case streamStartedMsg:
+		m.streaming = true
+		m.streamQuestion = msg.question
+		m.streamAnswer = ""
+		m.sources = msg.sources
+		m.status = ""
+		m.input = ""
+		return m, waitForToken(msg.tokens)
+
+	case streamTokenMsg:
+		if !msg.ok {
+			rendered := m.render(m.streamAnswer)
+			m.history = append(m.history, fmt.Sprintf("❓ %s", m.streamQuestion), rendered)
+			m.streaming = false
+			m.streamQuestion = ""
+			m.streamAnswer = ""
+			return m, nil
+		}
+		m.streamAnswer += msg.content
+		return m, waitForToken(msg.tokens)
+
+	case editorContentMsg:
+		m.input = string(msg)
+		m.status = ""
+
+	case errMsg:
+		m.status = fmt.Sprintf("❌ %v", msg.err)
+	}
+
+	return m, nil
+}
+
+func (m tuiModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.focus == panePrompt && m.editing {
+		switch msg.String() {
+		case "esc":
+			m.editing = false
+		case "enter":
+			if strings.TrimSpace(m.input) == "" {
+				return m, nil
+			}
+			question := m.input
+			m.status = "🤔 Sto pensando..."
+			return m, m.ask(question)
+		case "backspace":
+			if len(m.input) > 0 {
+				m.input = m.input[:len(m.input)-1]
+			}
+		case "ctrl+e":
+			return m, m.openEditor()
+		case "ctrl+c":
+			return m, tea.Quit
+		default:
+			m.input += msg.String()
+		}
+		return m, nil
+	}
+
+	// Modalità normale (vi-like): h/l cambiano pane, i torna in modalità
+	// inserimento sul pane prompt, q esce.
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "tab", "l":
+		m.focus = (m.focus + 1) % 3
+	case "h":
+		m.focus = (m.focus + 2) % 3
+	case "i":
+		m.focus = panePrompt
+		m.editing = true
+	}
+
+	return m, nil
+}
+
+func (m tuiModel) ask(question string) tea.Cmd {
+	return func() tea.Msg {
+		sources, tokens, err := m.chatbot.ChatStream(question)
+		if err != nil {
+			return errMsg{err}
+		}
+		return streamStartedMsg{question: question, sources: sources, tokens: tokens}
+	}
+}
+
+// waitForToken attende il prossimo token sul canale aperto da ask() e lo
+// trasforma in un tea.Msg, riarmandosi a ogni Update finché il canale non
+// si chiude: è così che bubbletea riceve la risposta pezzo per pezzo
+// invece che tutta insieme a fine generazione.
+func waitForToken(tokens <-chan string) tea.Cmd {
+	return func() tea.Msg {
+		content, ok := <-tokens
+		return streamTokenMsg{tokens: tokens, content: content, ok: ok}
+	}
+}
+
+// openEditor invoca $EDITOR su un file temporaneo per comporre domande
+// lunghe, poi ricarica il contenuto digitato nel pane prompt.
+func (m tuiModel) openEditor() tea.Cmd {
+	return func() tea.Msg {
+		editor := os.Getenv("EDITOR")
+		if editor == "" {
+			editor = "vi"
+		}
+
+		tmpFile, err := os.CreateTemp("", "go-ollama3-prompt-*.md")
+		if err != nil {
+			return errMsg{err}
+		}
+		tmpPath := tmpFile.Name()
+		defer os.Remove(tmpPath)
+
+		if _, err := tmpFile.WriteString(m.input); err != nil {
+			tmpFile.Close()
+			return errMsg{err}
+		}
+		tmpFile.Close()
+
+		cmd := exec.Command(editor, tmpPath)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errMsg{fmt.Errorf("errore avvio $EDITOR: %v", err)}
+		}
+
+		content, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return errMsg{err}
+		}
+
+		return editorContentMsg(strings.TrimSpace(string(content)))
+	}
+}
+
+// render applica la sintassi evidenziata dei blocchi di codice markdown
+// alla risposta del modello, con fallback al testo grezzo in caso di
+// errore di rendering.
+func (m tuiModel) render(answer string) string {
+	rendered, err := m.renderer.Render(answer)
+	if err != nil {
+		return answer
+	}
+	return rendered
+}
+
+func (m tuiModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(m.paneTitle("Conversazione", paneConversation))
+	b.WriteString(strings.Join(m.history, "\n"))
+	if m.streaming {
+		b.WriteString(fmt.Sprintf("\n❓ %s\n%s", m.streamQuestion, m.streamAnswer))
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString(m.paneTitle("Fonti recuperate", paneSources))
+	if len(m.sources) == 0 {
+		b.WriteString("(nessuna fonte ancora recuperata)\n")
+	} else {
+		for i, doc := range m.sources {
+			b.WriteString(fmt.Sprintf("🔹 Fonte %d (%s): %s\n", i+1, citationLabel(doc), previewContent(doc.Content, 120)))
+		}
+	}
+	b.WriteString("\n")
+
+	b.WriteString(m.paneTitle("Prompt (Ctrl+E per $EDITOR, Esc per modalità normale, q per uscire)", panePrompt))
+	b.WriteString("> ")
+	b.WriteString(m.input)
+	if m.editing {
+		b.WriteString("▌")
+	}
+	b.WriteString("\n")
+
+	if m.status != "" {
+		b.WriteString("\n" + m.status + "\n")
+	}
+
+	return b.String()
+}
+
+func (m tuiModel) paneTitle(title string, p pane) string {
+	marker := "  "
+	if m.focus == p {
+		marker = "▶ "
+	}
+	return fmt.Sprintf("%s%s\n%s\n", marker, title, strings.Repeat("─", len(title)+2))
+}